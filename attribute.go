@@ -0,0 +1,64 @@
+// attribute.go
+package main
+
+import "github.com/google/pprof/profile"
+
+// outsideProject is the frame that samples which never pass through project
+// code are charged to in the project-attributed view.
+const outsideProject = "(outside project code)"
+
+// attributeToProject returns a copy of p in which every stack keeps only its
+// project frames, classified like classifyFunction does. The cost of runtime,
+// stdlib and third-party code then lands on the deepest project function that
+// called it, as that function's own cost. Samples without any project frame
+// are charged to a single outsideProject frame, so totals still add up.
+func attributeToProject(p *profile.Profile, modulePaths []string) *profile.Profile {
+	if p == nil {
+		return nil
+	}
+	attributed := p.Copy()
+	isProject := make(map[uint64]bool, len(attributed.Function))
+	var maxFuncID, maxLocID uint64
+	for _, fn := range attributed.Function {
+		isProject[fn.ID] = classifyFunction(fn.Name, fn.Filename, modulePaths) == classProject
+		maxFuncID = max(maxFuncID, fn.ID)
+	}
+
+	// Inlined calls share a location, so only its project lines are kept.
+	locations := attributed.Location[:0]
+	for _, loc := range attributed.Location {
+		maxLocID = max(maxLocID, loc.ID)
+		lines := loc.Line[:0]
+		for _, line := range loc.Line {
+			if line.Function != nil && isProject[line.Function.ID] {
+				lines = append(lines, line)
+			}
+		}
+		loc.Line = lines
+		if len(lines) > 0 {
+			locations = append(locations, loc)
+		}
+	}
+	attributed.Location = locations
+
+	var outside *profile.Location
+	for _, s := range attributed.Sample {
+		stack := s.Location[:0]
+		for _, loc := range s.Location {
+			if len(loc.Line) > 0 {
+				stack = append(stack, loc)
+			}
+		}
+		if len(stack) == 0 {
+			if outside == nil {
+				fn := &profile.Function{ID: maxFuncID + 1, Name: outsideProject, SystemName: outsideProject}
+				outside = &profile.Location{ID: maxLocID + 1, Line: []profile.Line{{Function: fn}}}
+				attributed.Function = append(attributed.Function, fn)
+				attributed.Location = append(attributed.Location, outside)
+			}
+			stack = append(stack, outside)
+		}
+		s.Location = stack
+	}
+	return attributed
+}
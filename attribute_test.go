@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/google/pprof/profile"
+)
+
+// newAttributionTestProfile builds a profile of a service whose handler
+// spends most of its time in encoding/json and the allocator.
+func newAttributionTestProfile() *profile.Profile {
+	funcs := []*profile.Function{
+		{ID: 1, Name: "main.main"},
+		{ID: 2, Name: "github.com/org/svc/api.Handle"},
+		{ID: 3, Name: "github.com/org/svc/api.decode"},
+		{ID: 4, Name: "encoding/json.Unmarshal"},
+		{ID: 5, Name: "runtime.mallocgc"},
+		{ID: 6, Name: "runtime.gcBgMarkWorker"},
+	}
+	line := func(id int) profile.Line { return profile.Line{Function: funcs[id-1], Line: 1} }
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Function:   funcs,
+		Location: []*profile.Location{
+			{ID: 1, Line: []profile.Line{line(1)}},
+			{ID: 2, Line: []profile.Line{line(2)}},
+			{ID: 3, Line: []profile.Line{line(4), line(3)}}, // json.Unmarshal inlined into decode
+			{ID: 4, Line: []profile.Line{line(5)}},
+			{ID: 5, Line: []profile.Line{line(6)}},
+		},
+	}
+	stack := func(value int64, ids ...int) {
+		s := &profile.Sample{Value: []int64{value}}
+		for _, id := range ids {
+			s.Location = append(s.Location, p.Location[id-1])
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	stack(40, 4, 3, 2, 1) // mallocgc <- json.Unmarshal/decode <- Handle <- main
+	stack(10, 3, 2, 1)
+	stack(25, 4, 2, 1)
+	stack(20, 5) // GC worker, no project frame
+	return p
+}
+
+func TestAttributeToProject(t *testing.T) {
+	p := newAttributionTestProfile()
+	attributed := attributeToProject(p, []string{"github.com/org/svc"})
+	data, err := NewProfileData(attributed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := data.Views[0]
+	if view.TotalValue != 95 {
+		t.Errorf("total = %d, want 95", view.TotalValue)
+	}
+	for _, want := range []struct {
+		name      string
+		flat, cum int64
+	}{
+		{"github.com/org/svc/api.decode", 50, 50},
+		{"github.com/org/svc/api.Handle", 25, 75},
+		{"main.main", 0, 75},
+		{outsideProject, 20, 20},
+	} {
+		node := findNode(view, want.name)
+		if node == nil || node.FlatValue != want.flat || node.CumValue != want.cum {
+			t.Errorf("%s = %+v, want flat %d and cum %d", want.name, node, want.flat, want.cum)
+		}
+	}
+	for _, name := range []string{"encoding/json.Unmarshal", "runtime.mallocgc", "runtime.gcBgMarkWorker"} {
+		if findNode(view, name) != nil {
+			t.Errorf("%s should not appear in the project-attributed view", name)
+		}
+	}
+	if len(p.Location[2].Line) != 2 || findNode(mustProfileData(t, p).Views[0], "runtime.mallocgc") == nil {
+		t.Error("the original profile must not be modified")
+	}
+
+	root := BuildFlameGraph(attributed, 0, view.Unit, "")
+	if decode := findNodeByName(root, "github.com/org/svc/api.decode"); decode == nil || decode.Value != 50 || len(decode.Children) != 0 {
+		t.Errorf("flame graph decode frame = %+v, want a 50 leaf", decode)
+	}
+}
+
+func mustProfileData(t *testing.T, p *profile.Profile) *ProfileData {
+	t.Helper()
+	data, err := NewProfileData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAttributedViewSurvivesLiveRefresh(t *testing.T) {
+	sized, _ := newModel(mustProfileData(t, newAttributionTestProfile()), "live").Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	m := sized.(model)
+	m.isLiveMode = true
+	m.modulePaths = []string{"github.com/org/svc"}
+	m.attributed = true
+	m.reloadProfile()
+
+	updated, _ := m.Update(profileUpdateMsg{data: mustProfileData(t, newAttributionTestProfile())})
+	view := updated.(model).profileData.Views[0]
+	if findNode(view, "runtime.mallocgc") != nil {
+		t.Error("a live refresh should keep the project-attributed view")
+	}
+	if decode := findNode(view, "github.com/org/svc/api.decode"); decode == nil || decode.FlatValue != 50 {
+		t.Errorf("decode = %+v, want flat 50", decode)
+	}
+}
@@ -0,0 +1,144 @@
+// classify.go
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CodeClass tells where a function's code comes from.
+type CodeClass int
+
+const (
+	classThirdParty CodeClass = iota // Dependencies, and code that is not Go.
+	classProject                     // The modules of the user's project.
+	classStdlib                      // The standard library, except the runtime.
+	classRuntime                     // The runtime and its internal packages.
+)
+
+// classAll is not a class of its own: as a filter it shows every class.
+const classAll CodeClass = -1
+
+// classFilterCycle is the order `p` steps through the class filters.
+var classFilterCycle = []CodeClass{classAll, classProject, classStdlib, classRuntime, classThirdParty}
+
+func (c CodeClass) String() string {
+	switch c {
+	case classProject:
+		return "project"
+	case classStdlib:
+		return "stdlib"
+	case classRuntime:
+		return "runtime"
+	case classThirdParty:
+		return "third-party"
+	}
+	return "all"
+}
+
+// nextClassFilter returns the class filter after c in classFilterCycle.
+func nextClassFilter(c CodeClass) CodeClass {
+	for i, class := range classFilterCycle {
+		if class == c {
+			return classFilterCycle[(i+1)%len(classFilterCycle)]
+		}
+	}
+	return classAll
+}
+
+// detectModulePaths returns the module paths of the project containing dir:
+// every module used by the closest go.work, or else the closest go.mod's,
+// the same files the go command would pick.
+func detectModulePaths(dir string) []string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if uses := readGoWorkUses(filepath.Join(d, "go.work")); uses != nil {
+			var paths []string
+			for _, use := range uses {
+				if modulePath, _ := readGoMod(filepath.Join(d, use, "go.mod")); modulePath != "" {
+					paths = append(paths, modulePath)
+				}
+			}
+			return paths
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if modulePath, _ := readGoMod(filepath.Join(d, "go.mod")); modulePath != "" {
+			return []string{modulePath}
+		}
+		if filepath.Dir(d) == d {
+			return nil
+		}
+	}
+}
+
+// readGoWorkUses returns the module directories listed by the use directives
+// of a go.work file, or nil if there is no such file.
+func readGoWorkUses(goWork string) []string {
+	f, err := os.Open(goWork)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	uses := []string{}
+	inUse := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inUse && fields[0] == ")":
+			inUse = false
+		case inUse:
+			uses = append(uses, strings.Trim(fields[0], `"`))
+		case fields[0] == "use" && len(fields) == 2 && fields[1] == "(":
+			inUse = true
+		case fields[0] == "use" && len(fields) >= 2:
+			uses = append(uses, strings.Trim(fields[1], `"`))
+		}
+	}
+	return uses
+}
+
+// classifyFunction sorts a function into a CodeClass by its package, taken
+// from the function name, or by its file for project code built outside the
+// module's own directory layout. Package main is always project code.
+func classifyFunction(name, fileName string, modulePaths []string) CodeClass {
+	pkg := funcPackage(name)
+	if pkg == "main" {
+		return classProject
+	}
+	for _, modulePath := range modulePaths {
+		modulePath = strings.TrimSuffix(modulePath, "/")
+		if pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/") || strings.Contains(fileName, modulePath+"/") {
+			return classProject
+		}
+	}
+	switch {
+	case pkg == "runtime" || strings.HasPrefix(pkg, "runtime/") || strings.HasPrefix(pkg, "internal/runtime/"):
+		return classRuntime
+	case pkg != "" && !strings.Contains(strings.SplitN(pkg, "/", 2)[0], "."):
+		// Standard library import paths have no dot in their first element.
+		return classStdlib
+	}
+	return classThirdParty
+}
+
+// classifyCode sets the class of every function of the profile, marking the
+// ones of the given modules as project code.
+func classifyCode(data *ProfileData, modulePaths []string) {
+	if data == nil {
+		return
+	}
+	for _, view := range data.Views {
+		for _, node := range view.Nodes {
+			node.Class = classifyFunction(node.Name, node.FileName, modulePaths)
+			node.IsProjectCode = node.Class == classProject
+		}
+	}
+}
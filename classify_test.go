@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyFunction(t *testing.T) {
+	modules := []string{"github.com/org/svc", "github.com/org/tools/"}
+	for _, tc := range []struct {
+		name, file string
+		want       CodeClass
+	}{
+		{"github.com/org/svc/api.(*Server).handle", "/build/api/server.go", classProject},
+		{"github.com/org/svc.Run", "", classProject},
+		{"github.com/org/tools/gen.Generate", "", classProject},
+		{"main.main", "/src/cmd/svc/main.go", classProject},
+		{"github.com/org/svc-fork.Run", "", classThirdParty},
+		{"example.com/plugin.Load", "/home/ci/src/github.com/org/svc/plugin/load.go", classProject},
+		{"runtime.mallocgc", "/usr/local/go/src/runtime/malloc.go", classRuntime},
+		{"internal/runtime/maps.(*Map).Get", "", classRuntime},
+		{"runtime/pprof.writeHeap", "", classRuntime},
+		{"encoding/json.(*decodeState).object", "", classStdlib},
+		{"vendor/golang.org/x/net/http2/hpack.(*Decoder).Write", "", classStdlib},
+		{"github.com/lib/pq.(*conn).query", "", classThirdParty},
+		{"gopkg.in/yaml%2ev3.(*decoder).unmarshal", "", classThirdParty},
+		{"std::vector<int>::push_back(int const&)", "", classThirdParty},
+		{"[unknown] (libfoo.so)", "", classThirdParty},
+	} {
+		if got := classifyFunction(tc.name, tc.file, modules); got != tc.want {
+			t.Errorf("classifyFunction(%s) = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectModulePaths(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("svc/go.mod", "module github.com/org/svc\n")
+	writeFile("lib/go.mod", "module github.com/org/lib\n")
+	writeFile("svc/internal/api/api.go", "package api\n")
+
+	dir := filepath.Join(root, "svc", "internal", "api")
+	if got := detectModulePaths(dir); len(got) != 1 || got[0] != "github.com/org/svc" {
+		t.Errorf("without go.work: %v, want [github.com/org/svc]", got)
+	}
+
+	writeFile("go.work", "go 1.22\n\nuse (\n\t./svc\n\t./lib // shared code\n)\n")
+	if got := detectModulePaths(dir); len(got) != 2 || got[0] != "github.com/org/svc" || got[1] != "github.com/org/lib" {
+		t.Errorf("with go.work: %v, want both modules", got)
+	}
+}
+
+func TestNextClassFilter(t *testing.T) {
+	c := classAll
+	var seen []CodeClass
+	for range classFilterCycle {
+		c = nextClassFilter(c)
+		seen = append(seen, c)
+	}
+	want := []CodeClass{classProject, classStdlib, classRuntime, classThirdParty, classAll}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("p cycles through %v, want %v", seen, want)
+		}
+	}
+}
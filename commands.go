@@ -18,7 +18,7 @@ func tickerCmd(interval time.Duration) tea.Cmd {
 }
 
 // fetchProfileCmd performs the HTTP GET, parsing, and annotation in the background.
-func fetchProfileCmd(url, modulePath string) tea.Cmd {
+func fetchProfileCmd(url string, modulePaths []string, symbolizer *Symbolizer) tea.Cmd {
 	return func() tea.Msg {
 		// Fetch the profile data from the URL
 		resp, err := http.Get(url)
@@ -33,16 +33,13 @@ func fetchProfileCmd(url, modulePath string) tea.Cmd {
 		}
 
 		// Parse the new data
-		profileData, err := ParsePprofFile(resp.Body)
+		profileData, err := ParsePprofFile(resp.Body, symbolizer)
 		if err != nil {
 			return profileUpdateErr{fmt.Errorf("parse failed: %w", err)}
 		}
 
-		// Annotate with project code if path is provided.
-		// This ensures live updates respect the module path.
-		if modulePath != "" {
-			annotateProjectCode(profileData, modulePath)
-		}
+		// Classify the code so live updates keep the project's functions marked.
+		classifyCode(profileData, modulePaths)
 
 		return profileUpdateMsg{data: profileData}
 	}
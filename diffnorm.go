@@ -0,0 +1,102 @@
+// diffnorm.go
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// DiffNormalization selects how the two profiles of a diff are made comparable.
+type DiffNormalization int
+
+const (
+	diffAbsolute DiffNormalization = iota // Raw values are subtracted.
+	diffShare                             // Each value is taken as a share of its profile's total.
+	diffRate                              // Each value is divided by its profile's duration.
+)
+
+func (n DiffNormalization) String() string {
+	switch n {
+	case diffShare:
+		return "share of total"
+	case diffRate:
+		return "per second"
+	}
+	return "absolute"
+}
+
+// diffNormalizer turns the raw before and after values of a diff view into
+// comparable quantities for the selected normalization.
+type diffNormalizer struct {
+	mode       DiffNormalization
+	before     float64 // Scale applied to values of the "before" profile.
+	after      float64 // Scale applied to values of the "after" profile.
+	impactBase float64 // What a delta is compared against to judge its impact.
+}
+
+// newDiffNormalizer builds the normalizer for a diff view. Modes that need data
+// the profiles do not have, like a duration, fall back to absolute values.
+func newDiffNormalizer(mode DiffNormalization, view *ProfileView, data *ProfileData) diffNormalizer {
+	switch mode {
+	case diffShare:
+		if view.BeforeTotal != 0 && view.AfterTotal != 0 {
+			return diffNormalizer{
+				mode:       diffShare,
+				before:     1 / float64(view.BeforeTotal),
+				after:      1 / float64(view.AfterTotal),
+				impactBase: 1,
+			}
+		}
+	case diffRate:
+		if canNormalizeByRate(data) {
+			afterScale := 1e9 / float64(data.DurationNanos)
+			return diffNormalizer{
+				mode:       diffRate,
+				before:     1e9 / float64(data.BaseDurationNanos),
+				after:      afterScale,
+				impactBase: float64(view.AfterTotal) * afterScale,
+			}
+		}
+	}
+	return diffNormalizer{mode: diffAbsolute, before: 1, after: 1, impactBase: float64(view.TotalValue)}
+}
+
+// canNormalizeByRate reports whether both profiles of a diff recorded their duration.
+func canNormalizeByRate(data *ProfileData) bool {
+	return data != nil && data.DurationNanos > 0 && data.BaseDurationNanos > 0
+}
+
+// nextDiffNormalization cycles to the next mode, skipping per-second rates when
+// the profiles have no duration.
+func nextDiffNormalization(current DiffNormalization, data *ProfileData) DiffNormalization {
+	next := (current + 1) % 3
+	if next == diffRate && !canNormalizeByRate(data) {
+		next = diffAbsolute
+	}
+	return next
+}
+
+// delta returns the normalized change between a before and an after value.
+func (d diffNormalizer) delta(before, after int64) float64 {
+	return float64(after)*d.after - float64(before)*d.before
+}
+
+// ratio returns the normalized after/before ratio, with the same conventions
+// as calculateRatio for new and removed functions.
+func (d diffNormalizer) ratio(before, after int64) float64 {
+	if before == 0 || after == 0 || d.mode == diffAbsolute {
+		return calculateRatio(before, after)
+	}
+	return (float64(after) * d.after) / (float64(before) * d.before)
+}
+
+// format renders a non-negative normalized value.
+func (d diffNormalizer) format(value float64, unit string) string {
+	switch d.mode {
+	case diffShare:
+		return fmt.Sprintf("%.2fpp", value*100) // Percentage points of the total.
+	case diffRate:
+		return formatValue(int64(math.Round(value)), unit) + "/s"
+	}
+	return formatValue(int64(math.Round(value)), unit)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDiffNormalizer(t *testing.T) {
+	before := newTestProfile(t)
+	before.DurationNanos = 10e9
+	after := newTestProfile(t)
+	after.DurationNanos = 20e9
+	for _, s := range after.Sample {
+		s.Value[0] *= 2 // Twice the samples over twice the time: nothing really changed.
+	}
+
+	data, err := DiffPprofFiles(writeTestProfile(t, before), writeTestProfile(t, after), nil)
+	if err != nil {
+		t.Fatalf("DiffPprofFiles: %v", err)
+	}
+	view := data.Views[0]
+	helper := findNode(view, "main.helper")
+
+	absolute := newDiffNormalizer(diffAbsolute, view, data)
+	if got := absolute.delta(helper.CumBefore, helper.CumAfter); got != 50 {
+		t.Errorf("absolute delta = %v, want 50", got)
+	}
+
+	for _, mode := range []DiffNormalization{diffShare, diffRate} {
+		norm := newDiffNormalizer(mode, view, data)
+		if norm.mode != mode {
+			t.Fatalf("newDiffNormalizer(%s) fell back to %s", mode, norm.mode)
+		}
+		if got := norm.delta(helper.CumBefore, helper.CumAfter); math.Abs(got) > 1e-9 {
+			t.Errorf("%s delta = %v, want 0", mode, got)
+		}
+		if got := norm.ratio(helper.CumBefore, helper.CumAfter); math.Abs(got-1) > 1e-9 {
+			t.Errorf("%s ratio = %v, want 1", mode, got)
+		}
+	}
+
+	data.BaseDurationNanos = 0
+	if got := newDiffNormalizer(diffRate, view, data).mode; got != diffAbsolute {
+		t.Errorf("rate normalization without durations = %s, want absolute", got)
+	}
+	if got := nextDiffNormalization(diffShare, data); got != diffAbsolute {
+		t.Errorf("nextDiffNormalization should skip rates without durations, got %s", got)
+	}
+}
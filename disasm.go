@@ -0,0 +1,334 @@
+// disasm.go
+package main
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"debug/gosym"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/pprof/profile"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// Instruction is a single decoded machine instruction of a function.
+type Instruction struct {
+	Addr     uint64
+	Size     int // Length in bytes.
+	Text     string
+	FileName string
+	Line     int
+}
+
+// Binary is a local ELF executable used to disassemble profiled functions
+// and to symbolize profiles of stripped binaries.
+type Binary struct {
+	Path    string
+	BuildID string // GNU build ID in hex, if the binary has one.
+	file    *elf.File
+	symbols []elf.Symbol // Function symbols sorted by address.
+	lines   *gosym.Table // Go pclntab, if the binary has one.
+
+	dwarfOnce  sync.Once // Loads dwarf and dwarfUnits on first use.
+	dwarf      *dwarf.Data
+	dwarfUnits []dwarfUnit
+}
+
+// OpenBinary opens an ELF binary and loads its symbol table.
+func OpenBinary(path string) (*Binary, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open binary: %w", err)
+	}
+	switch f.Machine {
+	case elf.EM_X86_64, elf.EM_386, elf.EM_AARCH64:
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unsupported architecture %s", f.Machine)
+	}
+
+	syms, err := f.Symbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) { // Stripped binaries may still have a pclntab.
+		f.Close()
+		return nil, fmt.Errorf("could not read symbols from %s: %w", path, err)
+	}
+	funcs := make([]elf.Symbol, 0, len(syms))
+	for _, sym := range syms {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
+			funcs = append(funcs, sym)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Value < funcs[j].Value })
+
+	return &Binary{
+		Path:    path,
+		BuildID: readBuildID(f),
+		file:    f,
+		symbols: funcs,
+		lines:   loadGoLineTable(f, funcs),
+	}, nil
+}
+
+// loadGoLineTable reads the Go pclntab so instructions can be mapped back to
+// source lines. It returns nil for binaries that were not built by Go.
+func loadGoLineTable(f *elf.File, funcs []elf.Symbol) *gosym.Table {
+	pclntab := f.Section(".gopclntab")
+	text := f.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil
+	}
+	pcln, err := pclntab.Data()
+	if err != nil {
+		return nil
+	}
+	textStart := text.Addr
+	for _, sym := range funcs {
+		if sym.Name == "runtime.text" {
+			textStart = sym.Value
+			break
+		}
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(pcln, textStart))
+	if err != nil {
+		return nil
+	}
+	return table
+}
+
+// Close releases the underlying ELF file.
+func (b *Binary) Close() error {
+	return b.file.Close()
+}
+
+// lookupSymbol returns the function symbol with the given name.
+func (b *Binary) lookupSymbol(name string) (elf.Symbol, bool) {
+	for _, sym := range b.symbols {
+		if sym.Name == name {
+			return sym, true
+		}
+	}
+	return elf.Symbol{}, false
+}
+
+// symbolAt resolves an address to the symbol containing it. It is used by the
+// disassemblers to print call and jump targets by name.
+func (b *Binary) symbolAt(addr uint64) (string, uint64) {
+	i := sort.Search(len(b.symbols), func(i int) bool { return b.symbols[i].Value > addr }) - 1
+	if i < 0 {
+		return "", 0
+	}
+	sym := b.symbols[i]
+	if sym.Size > 0 && addr >= sym.Value+sym.Size {
+		return "", 0
+	}
+	return sym.Name, sym.Value
+}
+
+// Disassemble decodes every instruction of the named function.
+func (b *Binary) Disassemble(funcName string) ([]Instruction, error) {
+	sym, ok := b.lookupSymbol(funcName)
+	if !ok {
+		return nil, fmt.Errorf("function %s not found in %s", funcName, b.Path)
+	}
+	if sym.Size == 0 {
+		return nil, fmt.Errorf("function %s has no size information in %s", funcName, b.Path)
+	}
+
+	var section *elf.Section
+	for _, s := range b.file.Sections {
+		if s.Type == elf.SHT_PROGBITS && sym.Value >= s.Addr && sym.Value+sym.Size <= s.Addr+s.Size {
+			section = s
+			break
+		}
+	}
+	if section == nil {
+		return nil, fmt.Errorf("no section contains function %s", funcName)
+	}
+	code := make([]byte, sym.Size)
+	if _, err := section.ReadAt(code, int64(sym.Value-section.Addr)); err != nil {
+		return nil, fmt.Errorf("could not read code for %s: %w", funcName, err)
+	}
+
+	var insts []Instruction
+	for offset := 0; offset < len(code); {
+		pc := sym.Value + uint64(offset)
+		text, size := b.decode(code[offset:], pc, section)
+		inst := Instruction{Addr: pc, Size: size, Text: text}
+		if b.lines != nil {
+			inst.FileName, inst.Line, _ = b.lines.PCToLine(pc)
+		}
+		insts = append(insts, inst)
+		offset += size
+	}
+	return insts, nil
+}
+
+// decode decodes a single instruction at pc, returning its Go assembler syntax
+// and its length in bytes. Undecodable bytes are reported as a single "?" byte.
+func (b *Binary) decode(code []byte, pc uint64, section *elf.Section) (string, int) {
+	switch b.file.Machine {
+	case elf.EM_X86_64, elf.EM_386:
+		mode := 64
+		if b.file.Machine == elf.EM_386 {
+			mode = 32
+		}
+		inst, err := x86asm.Decode(code, mode)
+		if err != nil || inst.Len == 0 {
+			return "?", 1
+		}
+		return x86asm.GoSyntax(inst, pc, b.symbolAt), inst.Len
+	case elf.EM_AARCH64:
+		if len(code) < 4 {
+			return "?", len(code)
+		}
+		inst, err := arm64asm.Decode(code)
+		if err != nil {
+			return "?", 4
+		}
+		return arm64asm.GoSyntax(inst, pc, b.symbolAt, section), 4
+	}
+	return "?", len(code)
+}
+
+// objAddr converts a runtime address recorded in a profile into the binary's
+// virtual address space, undoing the load offset of position-independent executables.
+func (b *Binary) objAddr(loc *profile.Location) uint64 {
+	if b.file.Type != elf.ET_DYN || loc.Mapping == nil || loc.Mapping.Start == 0 {
+		return loc.Address
+	}
+	fileOffset := loc.Address - loc.Mapping.Start + loc.Mapping.Offset
+	for _, prog := range b.file.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 &&
+			fileOffset >= prog.Off && fileOffset < prog.Off+prog.Filesz {
+			return fileOffset - prog.Off + prog.Vaddr
+		}
+	}
+	return loc.Address
+}
+
+// addressCosts sums the flat and cumulative sample values for every address of
+// the named function, taken from the raw profile's Location.Address. Given the
+// function's instructions, each address is charged to the instruction that
+// contains it: callers are recorded at their return address minus one, which
+// falls inside the CALL instruction rather than at its start.
+func (b *Binary) addressCosts(p *profile.Profile, sampleIndex int, funcName string, insts []Instruction) map[uint64]*LineCost {
+	costs := make(map[uint64]*LineCost)
+	if p == nil || sampleIndex >= len(p.SampleType) {
+		return costs
+	}
+	for _, s := range p.Sample {
+		val := s.Value[sampleIndex]
+		if val == 0 {
+			continue
+		}
+		seen := make(map[uint64]struct{})
+		for j, loc := range s.Location {
+			if !locationInFunction(loc, funcName) {
+				continue
+			}
+			addr := instructionAt(insts, b.objAddr(loc))
+			cost, ok := costs[addr]
+			if !ok {
+				cost = &LineCost{}
+				costs[addr] = cost
+			}
+			if j == 0 {
+				cost.Flat += val
+			}
+			if _, dup := seen[addr]; !dup {
+				seen[addr] = struct{}{}
+				cost.Cum += val
+			}
+		}
+	}
+	return costs
+}
+
+// instructionAt returns the address of the instruction containing addr, or
+// addr itself when it is outside the instructions.
+func instructionAt(insts []Instruction, addr uint64) uint64 {
+	i := sort.Search(len(insts), func(i int) bool { return insts[i].Addr > addr }) - 1
+	if i < 0 || addr >= insts[i].Addr+uint64(insts[i].Size) {
+		return addr
+	}
+	return insts[i].Addr
+}
+
+// hottestSymbol returns the name of the symbol containing the address with the highest cumulative cost.
+func (b *Binary) hottestSymbol(costs map[uint64]*LineCost) string {
+	var bestAddr uint64
+	var bestCum int64 = -1
+	for addr, cost := range costs {
+		if cost.Cum > bestCum {
+			bestAddr, bestCum = addr, cost.Cum
+		}
+	}
+	if bestCum < 0 {
+		return ""
+	}
+	name, _ := b.symbolAt(bestAddr)
+	return name
+}
+
+// locationInFunction reports whether any (possibly inlined) frame of loc belongs to funcName.
+func locationInFunction(loc *profile.Location, funcName string) bool {
+	for _, line := range loc.Line {
+		if line.Function != nil && line.Function.Name == funcName {
+			return true
+		}
+	}
+	return false
+}
+
+// getDisassembly renders the annotated disassembly of a function for the right-hand pane.
+func getDisassembly(b *Binary, p *profile.Profile, sampleIndex int, funcName, unit string) string {
+	if b == nil {
+		return "No binary loaded. Start pproftui with --binary <path> to see disassembly."
+	}
+	costs := b.addressCosts(p, sampleIndex, funcName, nil)
+
+	var result strings.Builder
+	symName := funcName
+	if _, ok := b.lookupSymbol(funcName); !ok {
+		// Inlined functions have no symbol of their own; show the function
+		// their hottest sampled instruction was inlined into instead.
+		if host := b.hottestSymbol(costs); host != "" {
+			symName = host
+			result.WriteString(fmt.Sprintf("%s is inlined into %s\n\n", funcName, host))
+		}
+	}
+	insts, err := b.Disassemble(symName)
+	if err != nil {
+		return fmt.Sprintf("Cannot disassemble:\n%v", err)
+	}
+	costs = b.addressCosts(p, sampleIndex, funcName, insts)
+
+	lastSource := ""
+	for _, inst := range insts {
+		source := ""
+		if inst.FileName != "" {
+			source = fmt.Sprintf("%s:%d", shortFileName(inst.FileName), inst.Line)
+		}
+		if source != lastSource {
+			// Show the source position only when it changes, like objdump does.
+			result.WriteString(fmt.Sprintf("%s%s\n", strings.Repeat(" ", 22), source))
+			lastSource = source
+		}
+		result.WriteString(fmt.Sprintf("%s%8x  %s\n", formatLineCostGutter(costs[inst.Addr], unit), inst.Addr, inst.Text))
+	}
+	return result.String()
+}
+
+// shortFileName trims a file path down to its last directory and base name.
+func shortFileName(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) <= 2 {
+		return path
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
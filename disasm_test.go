@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+//go:noinline
+func disasmTestTarget(n int) int {
+	if n > 1 {
+		return n * disasmTestTarget(n-1)
+	}
+	return 1
+}
+
+// Test binaries name package main after its import path.
+const disasmTestTargetName = "github.com/Oloruntobi1/pproftui.disasmTestTarget"
+
+// openTestBinary opens the running test executable.
+func openTestBinary(t *testing.T) *Binary {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	b, err := OpenBinary(exe)
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	// go test strips the symbol table of the binaries it runs, but they keep
+	// their pclntab, so the function symbols can be rebuilt from it.
+	if len(b.symbols) == 0 && b.lines != nil {
+		for _, fn := range b.lines.Funcs {
+			b.symbols = append(b.symbols, elf.Symbol{Name: fn.Name, Value: fn.Entry, Size: fn.End - fn.Entry})
+		}
+		sort.Slice(b.symbols, func(i, j int) bool { return b.symbols[i].Value < b.symbols[j].Value })
+	}
+	return b
+}
+
+func TestAddressCosts(t *testing.T) {
+	b := &Binary{file: &elf.File{FileHeader: elf.FileHeader{Type: elf.ET_EXEC}}}
+	work := &profile.Function{ID: 1, Name: "main.work"}
+	main := &profile.Function{ID: 2, Name: "main.main"}
+	loc := func(id, addr uint64, fn *profile.Function) *profile.Location {
+		return &profile.Location{ID: id, Address: addr, Line: []profile.Line{{Function: fn}}}
+	}
+	work10, work20, mainCall := loc(1, 0x1010, work), loc(2, 0x1020, work), loc(3, 0x2000, main)
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{work10, mainCall}, Value: []int64{5}},
+			// main.work recursing through the same call site counts once.
+			{Location: []*profile.Location{work20, work10, work10, mainCall}, Value: []int64{3}},
+			{Location: []*profile.Location{mainCall}, Value: []int64{0}},
+		},
+	}
+
+	costs := b.addressCosts(p, 0, "main.work", nil)
+	for _, want := range []struct {
+		addr      uint64
+		flat, cum int64
+	}{
+		{0x1010, 5, 8},
+		{0x1020, 3, 3},
+	} {
+		cost := costs[want.addr]
+		if cost == nil || cost.Flat != want.flat || cost.Cum != want.cum {
+			t.Errorf("%#x = %+v, want flat %d and cum %d", want.addr, cost, want.flat, want.cum)
+		}
+	}
+	if _, ok := costs[0x2000]; ok || len(costs) != 2 {
+		t.Errorf("costs = %v, want only main.work's addresses", costs)
+	}
+	if costs := b.addressCosts(p, 1, "main.work", nil); len(costs) != 0 {
+		t.Errorf("out of range sample index gave %v", costs)
+	}
+
+	// Addresses inside an instruction, like return addresses minus one, are
+	// charged to that instruction.
+	insts := []Instruction{{Addr: 0x1000, Size: 0x10}, {Addr: 0x1010, Size: 5}, {Addr: 0x1015, Size: 0xc}}
+	costs = b.addressCosts(p, 0, "main.work", insts)
+	if cost := costs[0x1010]; cost == nil || cost.Flat != 5 || cost.Cum != 8 {
+		t.Errorf("0x1010 = %+v, want flat 5 and cum 8", cost)
+	}
+	if cost := costs[0x1015]; cost == nil || cost.Flat != 3 || cost.Cum != 3 {
+		t.Errorf("0x1020 should be charged to the instruction at 0x1015, got %+v", costs)
+	}
+	if got := instructionAt(insts, 0x1030); got != 0x1030 {
+		t.Errorf("address past the last instruction = %#x, want it unchanged", got)
+	}
+}
+
+func TestObjAddr(t *testing.T) {
+	// An executable segment at file offset 0x1000, linked at 0x401000.
+	progs := []*elf.Prog{
+		{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Flags: elf.PF_R, Off: 0, Vaddr: 0x400000, Filesz: 0x1000}},
+		{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Flags: elf.PF_R | elf.PF_X, Off: 0x1000, Vaddr: 0x401000, Filesz: 0x5000}},
+	}
+	exec := &Binary{file: &elf.File{FileHeader: elf.FileHeader{Type: elf.ET_EXEC}, Progs: progs}}
+	pie := &Binary{file: &elf.File{FileHeader: elf.FileHeader{Type: elf.ET_DYN}, Progs: progs}}
+
+	tests := []struct {
+		name    string
+		b       *Binary
+		mapping *profile.Mapping
+		addr    uint64
+		want    uint64
+	}{
+		{"executable", exec, &profile.Mapping{Start: 0x401000}, 0x401234, 0x401234},
+		{"PIE mapped from offset 0", pie, &profile.Mapping{Start: 0x555555554000}, 0x555555555234, 0x401234},
+		{"PIE mapped from the text segment", pie, &profile.Mapping{Start: 0x7f0000001000, Offset: 0x1000}, 0x7f0000001234, 0x401234},
+		{"PIE without mapping", pie, nil, 0x1234, 0x1234},
+		{"PIE with unknown mapping start", pie, &profile.Mapping{}, 0x1234, 0x1234},
+		{"outside executable segments", pie, &profile.Mapping{Start: 0x555555554000}, 0x555555554010, 0x555555554010},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.b.objAddr(&profile.Location{Address: tt.addr, Mapping: tt.mapping})
+			if got != tt.want {
+				t.Errorf("objAddr(%#x) = %#x, want %#x", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	disasmTestTarget(3)
+	b := openTestBinary(t)
+	sym, ok := b.lookupSymbol(disasmTestTargetName)
+	if !ok {
+		t.Skip("test binary has neither a symbol table nor a pclntab")
+	}
+
+	insts, err := b.Disassemble(disasmTestTargetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insts) == 0 || insts[0].Addr != sym.Value {
+		t.Fatalf("instructions should start at %#x, got %+v", sym.Value, insts)
+	}
+	var calls, returns int
+	for i, inst := range insts {
+		if i > 0 && inst.Addr <= insts[i-1].Addr {
+			t.Errorf("instruction %d at %#x does not follow %#x", i, inst.Addr, insts[i-1].Addr)
+		}
+		if inst.Addr >= sym.Value+sym.Size {
+			t.Errorf("instruction at %#x is past the end of the function", inst.Addr)
+		}
+		if strings.Contains(inst.Text, "disasmTestTarget") {
+			calls++
+		}
+		if strings.HasPrefix(inst.Text, "RET") {
+			returns++
+		}
+		// Padding after the function has no source position.
+		if (i == 0 || inst.FileName != "") && !strings.HasSuffix(inst.FileName, "disasm_test.go") {
+			t.Errorf("instruction at %#x is in %s:%d, want disasm_test.go", inst.Addr, inst.FileName, inst.Line)
+		}
+	}
+	if calls == 0 {
+		t.Error("the recursive call should name its target")
+	}
+	if returns == 0 {
+		t.Error("no RET instruction decoded")
+	}
+
+	if _, err := b.Disassemble("no.such.function"); err == nil {
+		t.Error("disassembling a missing function should fail")
+	}
+}
+
+func TestGetDisassembly(t *testing.T) {
+	b := openTestBinary(t)
+	sym, ok := b.lookupSymbol(disasmTestTargetName)
+	if !ok {
+		t.Skip("test binary has neither a symbol table nor a pclntab")
+	}
+	target := &profile.Function{ID: 1, Name: disasmTestTargetName}
+	inlined := &profile.Function{ID: 2, Name: "github.com/Oloruntobi1/pproftui.inlinedHelper"}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		Sample: []*profile.Sample{{
+			Location: []*profile.Location{{
+				ID:      1,
+				Address: sym.Value,
+				Line:    []profile.Line{{Function: inlined}, {Function: target}},
+			}},
+			Value: []int64{7},
+		}},
+	}
+
+	out := getDisassembly(b, p, 0, disasmTestTargetName, "count")
+	wantLine := formatLineCostGutter(&LineCost{Flat: 7, Cum: 7}, "count") + fmt.Sprintf("%8x", sym.Value)
+	if !strings.Contains(out, wantLine) {
+		t.Errorf("disassembly should annotate the sampled instruction with %q:\n%s", wantLine, out)
+	}
+	if !strings.Contains(out, "disasm_test.go:") {
+		t.Errorf("disassembly should show source positions:\n%s", out)
+	}
+
+	// Functions without a symbol are shown inside the function they were inlined into.
+	out = getDisassembly(b, p, 0, inlined.Name, "count")
+	if !strings.HasPrefix(out, inlined.Name+" is inlined into "+disasmTestTargetName) || !strings.Contains(out, wantLine) {
+		t.Errorf("inlined function should be shown in its host:\n%s", out)
+	}
+
+	if out := getDisassembly(nil, p, 0, disasmTestTargetName, "count"); !strings.Contains(out, "--binary") {
+		t.Errorf("without a binary, got %q", out)
+	}
+}
+
+var disasmTestSink [][]byte
+
+//go:noinline
+func disasmTestAlloc() []byte { return make([]byte, 4096) }
+
+//go:noinline
+func disasmTestAllocCaller() {
+	for range 64 {
+		disasmTestSink = append(disasmTestSink, disasmTestAlloc())
+	}
+}
+
+func TestDisassemblyChargesCallInstructions(t *testing.T) {
+	b := openTestBinary(t)
+	if _, ok := b.lookupSymbol(disasmTestTargetName); !ok {
+		t.Skip("test binary has neither a symbol table nor a pclntab")
+	}
+
+	// A heap profile records its callers at their real return addresses.
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	disasmTestAllocCaller()
+	runtime.GC()
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampleIndex := -1
+	for i, st := range p.SampleType {
+		if st.Type == "alloc_objects" {
+			sampleIndex = i
+		}
+	}
+
+	const caller = "github.com/Oloruntobi1/pproftui.disasmTestAllocCaller"
+	insts, err := b.Disassemble(caller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	costs := b.addressCosts(p, sampleIndex, caller, insts)
+	var charged int64
+	for addr, cost := range costs {
+		i := slices.IndexFunc(insts, func(inst Instruction) bool { return inst.Addr == addr })
+		if i < 0 {
+			t.Errorf("cost charged to %#x, which is not an instruction", addr)
+			continue
+		}
+		if cost.Cum > 0 && strings.Contains(insts[i].Text, "CALL") {
+			charged += cost.Cum
+		}
+	}
+	if charged == 0 {
+		t.Fatalf("no cost charged to the CALL instructions of %s: %v", caller, costs)
+	}
+
+	out := getDisassembly(b, p, sampleIndex, caller, "count")
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "disasmTestAlloc(SB)") && strings.TrimSpace(line[:22]) != "" {
+			return
+		}
+	}
+	t.Errorf("the call to disasmTestAlloc should be annotated:\n%s", out)
+}
@@ -34,6 +34,8 @@ Analogy: Imagine checking how much water is in a bucket right now.
 What does "grows over time" mean?
 If you collect multiple profiles over time (e.g., every 30 seconds), and the in-use memory keeps increasing without going back down — even when the workload stays the same — it may indicate a memory leak.
 
+Open such a series with 'pproftui --series heap-*.pb.gz': every function shows a sparkline of its values over the series, and sorting by "Slope" puts the fastest-growing ones first.
+
 Use this view to detect memory leaks by watching for memory usage that trends upward over time without releasing memory.`,
 	},
 
@@ -76,7 +78,23 @@ Analogy: Think of your program as an office of workers. This view shows what eac
 - Waiting in line = Blocked on a channel or mutex
 - On hold = Waiting for I/O or another task
 
-Use this view to diagnose concurrency issues such as goroutines stuck waiting, deadlocks, or inefficient scheduling.`,
+Use this view to diagnose concurrency issues such as goroutines stuck waiting, deadlocks, or inefficient scheduling.
+
+Text dumps (/debug/pprof/goroutine?debug=2 or a panic traceback) carry more than the binary profile:
+- Every goroutine is labelled with its state ("chan receive", "IO wait", ...), how long it has been waiting and its ID (goid)
+- The list is grouped by state; press 'L' to focus on one state or wait bucket, or to group by another label
+- The "wait" view weighs each goroutine by how long it has been blocked (the runtime reports waits of a minute or more)`,
+	},
+
+	"trace": {
+		Title: "Execution Trace: Off-CPU Time",
+		Description: `These views are derived from a runtime/trace file, like 'go tool trace -pprof' does. They show time your goroutines spent *not* running, which a CPU profile cannot see:
+- net_blocking = Waiting on the network (reads, writes, accepts)
+- sync_blocking = Waiting on mutexes, channels, select and sync.Cond
+- syscall_blocking = Inside system calls such as file I/O
+- sched_latency = Ready to run but waiting for a free processor
+
+Press 't' to switch between them. High scheduler latency means there is more runnable work than GOMAXPROCS can serve; long blocking points at contention or slow I/O.`,
 	},
 
 	"mutex": {
@@ -137,9 +155,24 @@ Special indicators:
 - "eliminated" = Functions that disappeared
 - "2.0x faster/slower" = Performance ratio changes
 
+Repeated runs:
+- When each side is a set of profiles, deltas compare the mean of each set
+- "±" is the 95% confidence interval of the mean delta
+- "significant" changes pass Welch's t-test (p < 0.05); "~ noise" ones are within run-to-run variation
+
+Normalization ('%'):
+- Absolute compares raw values, which favors the profile that ran longer or under more load
+- Share of total compares each function's percentage of its own profile, in percentage points (pp)
+- Per second divides each profile by its duration, when both profiles recorded one
+
+Differential flame graph ('f'):
+- Frames are sized by the second profile; press 'w' to size them by the first
+- Red frames grew and blue frames shrank; the deeper the shade, the larger the change
+- Gray frames did not change; "new" and "gone" mark stacks found in only one profile
+
 Tips:
 - Focus on "major impact" items first
-- Press 'p' to show only your project code
+- Press 'p' to cycle between project, stdlib, runtime and third-party code
 - Look for patterns: did one slow function get replaced by a faster one?`,
 	},
 }
@@ -161,7 +194,10 @@ func getExplanationForView(viewName string) Explanation {
 	if strings.Contains(viewName, "alloc_space") {
 		return explainerMap["alloc_space"]
 	}
-	if strings.Contains(viewName, "goroutine") {
+	if strings.Contains(viewName, "_blocking") || strings.Contains(viewName, "sched_latency") {
+		return explainerMap["trace"]
+	}
+	if strings.Contains(viewName, "goroutine") || strings.HasPrefix(viewName, "wait ") {
 		return explainerMap["goroutine"]
 	}
 	// Default explanation
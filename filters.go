@@ -0,0 +1,236 @@
+// filters.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/pprof/profile"
+)
+
+// LabelMatch selects samples carrying a specific label value.
+type LabelMatch struct {
+	Key   string
+	Value string
+}
+
+func (l LabelMatch) String() string {
+	return l.Key + "=" + l.Value
+}
+
+// ProfileFilters are reductions applied to the raw samples before any view is built,
+// so that cumulative values and the flame graph reflect only the kept samples.
+type ProfileFilters struct {
+	Focus  *regexp.Regexp // Keep only stacks with a frame matching this.
+	Ignore *regexp.Regexp // Drop stacks with a frame matching this.
+	Hide   *regexp.Regexp // Remove matching frames but keep the sample.
+	Show   *regexp.Regexp // Remove all frames that do not match.
+
+	TagFocus  []LabelMatch // Keep only samples with one of these label values.
+	TagIgnore []LabelMatch // Drop samples with any of these label values.
+}
+
+// IsEmpty reports whether no filter is active.
+func (f ProfileFilters) IsEmpty() bool {
+	return f.Focus == nil && f.Ignore == nil && f.Hide == nil && f.Show == nil &&
+		len(f.TagFocus) == 0 && len(f.TagIgnore) == 0
+}
+
+// String summarizes the active filters for the header. It uses the same
+// key=value syntax accepted by the interactive filter prompt, quoting values
+// that contain spaces.
+func (f ProfileFilters) String() string {
+	var parts []string
+	for _, re := range []struct {
+		name string
+		re   *regexp.Regexp
+	}{{"focus", f.Focus}, {"ignore", f.Ignore}, {"hide", f.Hide}, {"show", f.Show}} {
+		if re.re != nil {
+			parts = append(parts, re.name+"="+quoteFilterValue(re.re.String()))
+		}
+	}
+	if len(f.TagFocus) > 0 {
+		parts = append(parts, "tagfocus="+quoteFilterValue(joinLabelMatches(f.TagFocus)))
+	}
+	if len(f.TagIgnore) > 0 {
+		parts = append(parts, "tagignore="+quoteFilterValue(joinLabelMatches(f.TagIgnore)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Apply returns a filtered copy of p. The original profile is never modified,
+// so filters can be changed or cleared later.
+func (f ProfileFilters) Apply(p *profile.Profile) *profile.Profile {
+	if p == nil || f.IsEmpty() {
+		return p
+	}
+	filtered := p.Copy()
+	if f.Focus != nil || f.Ignore != nil || f.Hide != nil || f.Show != nil {
+		filtered.FilterSamplesByName(f.Focus, f.Ignore, f.Hide, f.Show)
+	}
+
+	var focus, ignore profile.TagMatch
+	if len(f.TagFocus) > 0 {
+		focus = func(s *profile.Sample) bool { return sampleMatchesFocus(s, f.TagFocus) }
+	}
+	if len(f.TagIgnore) > 0 {
+		ignore = func(s *profile.Sample) bool { return sampleHasAnyLabel(s, f.TagIgnore) }
+	}
+	if focus != nil || ignore != nil {
+		filtered.FilterSamplesByTag(focus, ignore)
+	}
+	return filtered
+}
+
+// ParseFilters parses a space-separated list of key=value filters, such as
+// "focus=json ignore=runtime\. tagfocus=handler=/users". Values with spaces are
+// written in double quotes, as in tagfocus="state=chan receive". An empty
+// string clears every filter.
+func ParseFilters(spec string) (ProfileFilters, error) {
+	var f ProfileFilters
+	fields, err := splitFilterFields(spec)
+	if err != nil {
+		return ProfileFilters{}, err
+	}
+	for _, field := range fields {
+		key, value := field[0], field[1]
+		var err error
+		switch key {
+		case "focus":
+			f.Focus, err = compileFilterRegexp(key, value)
+		case "ignore":
+			f.Ignore, err = compileFilterRegexp(key, value)
+		case "hide":
+			f.Hide, err = compileFilterRegexp(key, value)
+		case "show":
+			f.Show, err = compileFilterRegexp(key, value)
+		case "tagfocus":
+			f.TagFocus, err = parseLabelMatches(value)
+		case "tagignore":
+			f.TagIgnore, err = parseLabelMatches(value)
+		default:
+			err = fmt.Errorf("unknown filter %q", key)
+		}
+		if err != nil {
+			return ProfileFilters{}, err
+		}
+	}
+	return f, nil
+}
+
+// splitFilterFields splits a filter spec into key and value pairs at spaces,
+// except within values quoted with Go string syntax.
+func splitFilterFields(spec string) ([][2]string, error) {
+	var fields [][2]string
+	rest := strings.TrimLeftFunc(spec, unicode.IsSpace)
+	for rest != "" {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		key, value, ok := strings.Cut(rest[:end], "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter %q, expected key=value", rest[:end])
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest[len(key)+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value for %s: %w", key, err)
+			}
+			value, _ = strconv.Unquote(quoted)
+			end = len(key) + 1 + len(quoted)
+			if end < len(rest) && !unicode.IsSpace(rune(rest[end])) {
+				return nil, fmt.Errorf("invalid filter %s: text after the quoted value", key)
+			}
+		}
+		fields = append(fields, [2]string{key, value})
+		rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+	}
+	return fields, nil
+}
+
+// quoteFilterValue quotes a filter value for ParseFilters if it would not
+// survive splitting at spaces.
+func quoteFilterValue(value string) string {
+	if strings.ContainsFunc(value, unicode.IsSpace) || strings.HasPrefix(value, `"`) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// compileFilterRegexp compiles a frame filter. An empty expression means no filter.
+func compileFilterRegexp(name, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s expression: %w", name, err)
+	}
+	return re, nil
+}
+
+// toggleLabel adds the label match to the list, or removes it if it was already present.
+func toggleLabel(matches []LabelMatch, match LabelMatch) []LabelMatch {
+	for i, m := range matches {
+		if m == match {
+			return append(matches[:i:i], matches[i+1:]...)
+		}
+	}
+	return append(matches, match)
+}
+
+// sampleMatchesFocus reports whether a sample satisfies the tag focus. Values of
+// the same key are alternatives; different keys must all match, like pprof's -tagfocus.
+func sampleMatchesFocus(s *profile.Sample, focus []LabelMatch) bool {
+	byKey := make(map[string][]LabelMatch)
+	for _, m := range focus {
+		byKey[m.Key] = append(byKey[m.Key], m)
+	}
+	for _, matches := range byKey {
+		if !sampleHasAnyLabel(s, matches) {
+			return false
+		}
+	}
+	return true
+}
+
+// sampleHasAnyLabel reports whether a sample carries at least one of the given label values.
+func sampleHasAnyLabel(s *profile.Sample, matches []LabelMatch) bool {
+	for _, m := range matches {
+		for _, v := range sampleLabelValues(s, m.Key) {
+			if v == m.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseLabelMatches parses a comma-separated list of key=value pairs, as
+// accepted by the --tagfocus and --tagignore flags.
+func parseLabelMatches(spec string) ([]LabelMatch, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var matches []LabelMatch
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label filter %q, expected key=value", part)
+		}
+		matches = append(matches, LabelMatch{Key: key, Value: value})
+	}
+	return matches, nil
+}
+
+func joinLabelMatches(matches []LabelMatch) string {
+	parts := make([]string, len(matches))
+	for i, m := range matches {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// newLabelledProfile returns a profile with one sample per handler label.
+func newLabelledProfile() *profile.Profile {
+	fn := &profile.Function{ID: 1, Name: "main.serve", Filename: "main.go"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn, Line: 7}}}
+	sample := func(handler, tenant string, val int64) *profile.Sample {
+		return &profile.Sample{
+			Location: []*profile.Location{loc},
+			Value:    []int64{val},
+			Label:    map[string][]string{"handler": {handler}, "tenant": {tenant}},
+		}
+	}
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		Function:   []*profile.Function{fn},
+		Location:   []*profile.Location{loc},
+		Sample: []*profile.Sample{
+			sample("/users", "a", 10),
+			sample("/users", "b", 20),
+			sample("/orders", "a", 40),
+		},
+	}
+}
+
+func totalValue(p *profile.Profile) int64 {
+	var total int64
+	for _, s := range p.Sample {
+		total += s.Value[0]
+	}
+	return total
+}
+
+func TestProfileFiltersApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters ProfileFilters
+		want    int64
+	}{
+		{
+			name:    "no filters",
+			filters: ProfileFilters{},
+			want:    70,
+		},
+		{
+			name:    "focus on one value",
+			filters: ProfileFilters{TagFocus: []LabelMatch{{"handler", "/users"}}},
+			want:    30,
+		},
+		{
+			name:    "values of the same key are alternatives",
+			filters: ProfileFilters{TagFocus: []LabelMatch{{"handler", "/users"}, {"handler", "/orders"}}},
+			want:    70,
+		},
+		{
+			name:    "different keys must all match",
+			filters: ProfileFilters{TagFocus: []LabelMatch{{"handler", "/users"}, {"tenant", "a"}}},
+			want:    10,
+		},
+		{
+			name:    "ignore",
+			filters: ProfileFilters{TagIgnore: []LabelMatch{{"tenant", "a"}}},
+			want:    20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newLabelledProfile()
+			got := totalValue(tt.filters.Apply(p))
+			if got != tt.want {
+				t.Errorf("filtered total = %d, want %d", got, tt.want)
+			}
+			if totalValue(p) != 70 {
+				t.Errorf("Apply modified the original profile")
+			}
+		})
+	}
+}
+
+func TestCollectLabels(t *testing.T) {
+	summaries := CollectLabels(newLabelledProfile(), 0)
+	want := []LabelSummary{
+		{LabelMatch{"handler", "/orders"}, 40},
+		{LabelMatch{"handler", "/users"}, 30},
+		{LabelMatch{"tenant", "a"}, 50},
+		{LabelMatch{"tenant", "b"}, 20},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("got %d summaries, want %d: %v", len(summaries), len(want), summaries)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("summary %d = %v, want %v", i, summaries[i], want[i])
+		}
+	}
+}
+
+func TestBuildFlameGraphPivot(t *testing.T) {
+	p := newLabelledProfile()
+	p.Sample = append(p.Sample, &profile.Sample{
+		Location: p.Sample[0].Location,
+		Value:    []int64{5},
+	})
+
+	root := BuildFlameGraph(p, 0, "count", "handler")
+	want := map[string]int64{
+		"handler=/orders":      40,
+		"handler=/users":       30,
+		"handler=(unlabelled)": 5,
+	}
+	if len(root.Children) != len(want) {
+		t.Fatalf("got %d pivot frames, want %d", len(root.Children), len(want))
+	}
+	for _, child := range root.Children {
+		if child.Value != want[child.Name] {
+			t.Errorf("%s = %d, want %d", child.Name, child.Value, want[child.Name])
+		}
+		if len(child.Children) != 1 || child.Children[0].Name != "main.serve" {
+			t.Errorf("%s should contain the real stack below it", child.Name)
+		}
+	}
+	if root.Value != 75 {
+		t.Errorf("root value = %d, want 75", root.Value)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	spec := `focus=json ignore=runtime\. hide=^reflect show=main tagfocus=handler=/users,handler=/orders tagignore=tenant=a`
+	f, err := ParseFilters(spec)
+	if err != nil {
+		t.Fatalf("ParseFilters: %v", err)
+	}
+	if got := f.String(); got != spec {
+		t.Errorf("String() = %q, want %q", got, spec)
+	}
+
+	if f, err := ParseFilters(""); err != nil || !f.IsEmpty() {
+		t.Errorf("empty spec should clear all filters, got %v (err %v)", f, err)
+	}
+	// Label values and expressions with spaces survive a round trip through
+	// the prompt, which is filled with String().
+	spaced := ProfileFilters{
+		Focus:     regexp.MustCompile(`flush buffers`),
+		TagFocus:  []LabelMatch{{Key: "state", Value: "chan receive"}, {Key: "comm", Value: "my worker"}},
+		TagIgnore: []LabelMatch{{Key: "bytes", Value: "4096 bytes"}},
+	}
+	f, err = ParseFilters(spaced.String())
+	if err != nil {
+		t.Fatalf("ParseFilters(%q): %v", spaced.String(), err)
+	}
+	if f.String() != spaced.String() || f.Focus.String() != "flush buffers" ||
+		!slices.Equal(f.TagFocus, spaced.TagFocus) || !slices.Equal(f.TagIgnore, spaced.TagIgnore) {
+		t.Errorf("round trip of %q gave %q", spaced.String(), f.String())
+	}
+	if f, err := ParseFilters(`tagfocus="state=IO wait"  focus=json`); err != nil || f.TagFocus[0].Value != "IO wait" || f.Focus.String() != "json" {
+		t.Errorf("quoted value parsed as %v (err %v)", f, err)
+	}
+
+	for _, bad := range []string{"focus", "focus=(", "colour=red", "tagfocus=handler", `focus="json`, `focus="a"b`} {
+		if _, err := ParseFilters(bad); err == nil {
+			t.Errorf("ParseFilters(%q) should fail", bad)
+		}
+	}
+}
+
+func TestProfileFiltersByName(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantTotal int64
+		wantFuncs []string
+	}{
+		{name: "focus", spec: "focus=main.helper", wantTotal: 50, wantFuncs: []string{"main.helper", "main.main", "main.work"}},
+		{name: "ignore", spec: "ignore=main.helper", wantTotal: 5, wantFuncs: []string{"main.main", "main.work"}},
+		{name: "hide", spec: "hide=main.helper", wantTotal: 55, wantFuncs: []string{"main.main", "main.work"}},
+		{name: "show", spec: "show=main.helper", wantTotal: 50, wantFuncs: []string{"main.helper"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilters(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseFilters: %v", err)
+			}
+			data, err := NewProfileData(f.Apply(newTestProfile(t)))
+			if err != nil {
+				t.Fatalf("NewProfileData: %v", err)
+			}
+			view := data.Views[0]
+			if view.TotalValue != tt.wantTotal {
+				t.Errorf("total = %d, want %d", view.TotalValue, tt.wantTotal)
+			}
+			if len(view.Nodes) != len(tt.wantFuncs) {
+				t.Errorf("got %d functions, want %v", len(view.Nodes), tt.wantFuncs)
+			}
+			for _, name := range tt.wantFuncs {
+				if findNode(view, name) == nil {
+					t.Errorf("function %s missing", name)
+				}
+			}
+		})
+	}
+}
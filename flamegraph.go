@@ -179,6 +179,52 @@ func getColorForPercentage(percentage float64) lipgloss.Color {
 	}
 }
 
+// getColorForDelta colors a differential frame by how much it changed relative
+// to its larger value: red shades for growth, blue shades for shrinkage.
+func getColorForDelta(before, after int64) lipgloss.Color {
+	delta := after - before
+	if delta == 0 {
+		return lipgloss.Color("250") // Unchanged - gray
+	}
+	base := max(before, after)
+	change := float64(abs(delta)) / float64(base) * 100
+	if delta > 0 {
+		switch {
+		case change >= 50.0:
+			return lipgloss.Color("196")
+		case change >= 20.0:
+			return lipgloss.Color("203")
+		case change >= 5.0:
+			return lipgloss.Color("210")
+		default:
+			return lipgloss.Color("224")
+		}
+	}
+	switch {
+	case change >= 50.0:
+		return lipgloss.Color("27")
+	case change >= 20.0:
+		return lipgloss.Color("33")
+	case change >= 5.0:
+		return lipgloss.Color("75")
+	default:
+		return lipgloss.Color("153")
+	}
+}
+
+// formatFlameDelta labels a differential frame with its relative change.
+func formatFlameDelta(node *FlameNode) string {
+	switch {
+	case node.BeforeValue == node.AfterValue:
+		return "unchanged"
+	case node.BeforeValue == 0:
+		return "new"
+	case node.AfterValue == 0:
+		return "gone"
+	}
+	return fmt.Sprintf("%+.1f%%", float64(node.AfterValue-node.BeforeValue)/float64(node.BeforeValue)*100)
+}
+
 // RenderFlameGraph renders the entire flame graph as a string.
 func RenderFlameGraph(root, focusNode, viewNode, hoveredNode *FlameNode, termWidth int, totalValue int64) (string, []FlameNodeRenderInfo) {
 	if root == nil || focusNode == nil || focusNode.Value == 0 || termWidth <= 0 {
@@ -248,6 +294,9 @@ func RenderFlameGraph(root, focusNode, viewNode, hoveredNode *FlameNode, termWid
 				percent = (float64(node.Value) / float64(totalValue)) * 100
 			}
 			color := getColorForPercentage(percent)
+			if node.Diff {
+				color = getColorForDelta(node.BeforeValue, node.AfterValue)
+			}
 			style := lipgloss.NewStyle().
 				Background(color).
 				Foreground(lipgloss.Color("232"))
@@ -263,9 +312,15 @@ func RenderFlameGraph(root, focusNode, viewNode, hoveredNode *FlameNode, termWid
 			}
 
 			// Truncate name logic
-			parts := strings.Split(node.Name, "/")
-			name := parts[len(parts)-1]
+			name := node.Name
+			if !node.Synthetic {
+				parts := strings.Split(node.Name, "/")
+				name = parts[len(parts)-1]
+			}
 			label := fmt.Sprintf("%s (%.1f%%)", name, percent)
+			if node.Diff {
+				label = fmt.Sprintf("%s (%s)", name, formatFlameDelta(node))
+			}
 			if lipgloss.Width(label) > nodeLayout.Width {
 				label = name
 			}
@@ -0,0 +1,102 @@
+// folded.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// foldedLineRE matches a collapsed stack line: frames separated by ';', root
+// first, then a space and the sample count.
+var foldedLineRE = regexp.MustCompile(`^(\S.*) (\d+)$`)
+
+// isFoldedStacks reports whether data looks like the collapsed stack format
+// produced by stackcollapse-* scripts, async-profiler and many eBPF tools.
+func isFoldedStacks(data []byte) bool {
+	checked := 0
+	for _, line := range leadingLines(data, sniffLines) {
+		if len(line) == 0 {
+			continue
+		}
+		if !foldedLineRE.Match(line) {
+			return false
+		}
+		checked++
+	}
+	return checked > 0
+}
+
+// ParseFoldedStacks converts collapsed stacks into a profile with one sample
+// type, "samples". Frames only carry a function name, so there is no source
+// to show for them.
+func ParseFoldedStacks(data []byte) (*profile.Profile, error) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "samples", Unit: "count"},
+		Period:     1,
+	}
+	locations := make(map[string]*profile.Location)
+	samples := make(map[string]*profile.Sample) // Identical stacks are summed.
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		m := foldedLineRE.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected \"frame;frame;... count\"", lineNo)
+		}
+		count, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid count: %w", lineNo, err)
+		}
+		if s, ok := samples[m[1]]; ok {
+			s.Value[0] += count
+			continue
+		}
+
+		frames := strings.Split(m[1], ";")
+		s := &profile.Sample{Value: []int64{count}}
+		// Folded stacks list the root first; pprof samples list the leaf first.
+		for i := len(frames) - 1; i >= 0; i-- {
+			name := frames[i]
+			if name == "" {
+				continue
+			}
+			loc, ok := locations[name]
+			if !ok {
+				fn := &profile.Function{
+					ID:         uint64(len(p.Function) + 1),
+					Name:       name,
+					SystemName: name,
+				}
+				p.Function = append(p.Function, fn)
+				loc = &profile.Location{
+					ID:   uint64(len(p.Location) + 1),
+					Line: []profile.Line{{Function: fn}},
+				}
+				p.Location = append(p.Location, loc)
+				locations[name] = loc
+			}
+			s.Location = append(s.Location, loc)
+		}
+		samples[m[1]] = s
+		p.Sample = append(p.Sample, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.Sample) == 0 {
+		return nil, fmt.Errorf("no stacks found")
+	}
+	return p, p.CheckValid()
+}
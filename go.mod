@@ -8,6 +8,7 @@ require (
 	github.com/charmbracelet/bubbletea v1.3.5
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/google/pprof v0.0.0-20250607225305-033d6d78b36a
+	golang.org/x/arch v0.18.0
 )
 
 require (
@@ -0,0 +1,246 @@
+// goroutines.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// Labels attached to every goroutine of a text dump. Goroutines that share a
+// sample list their IDs in the numeric goroutineIDLabel.
+const (
+	goroutineStateLabel = "state"
+	goroutineWaitLabel  = "wait"
+	goroutineIDLabel    = "goid"
+)
+
+var (
+	// goroutineHeaderRE matches "goroutine 42 [chan receive, 5 minutes]:", including
+	// the extra fields printed with GOTRACEBACK=system.
+	goroutineHeaderRE = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[(.*)\]:$`)
+	waitMinutesRE     = regexp.MustCompile(`^(\d+) minutes?$`)
+	frameFileRE       = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// isGoroutineDump reports whether data looks like the text served by
+// /debug/pprof/goroutine?debug=2 or printed by a panic.
+func isGoroutineDump(data []byte) bool {
+	for _, line := range leadingLines(data, sniffLines) {
+		if goroutineHeaderRE.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// goroutineRecord is one goroutine of a text dump.
+type goroutineRecord struct {
+	id     int64
+	state  string
+	wait   time.Duration
+	frames []goroutineFrame // Innermost frame first, like a pprof sample.
+}
+
+type goroutineFrame struct {
+	function string
+	file     string
+	line     int64
+}
+
+// ParseGoroutineDump converts a goroutine text dump into a profile. Goroutines
+// with the same stack, state and wait bucket share a sample, which carries the
+// state and wait bucket as labels and the goroutines' IDs as numeric labels,
+// and has two values: the number of goroutines and their total wait time.
+func ParseGoroutineDump(data []byte) (*profile.Profile, error) {
+	records, err := scanGoroutineDump(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no goroutines found in dump")
+	}
+
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "goroutine", Unit: "count"},
+			{Type: "wait", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "goroutine", Unit: "count"},
+		Period:     1,
+	}
+	functions := make(map[[2]string]*profile.Function)
+	locations := make(map[goroutineFrame]*profile.Location)
+	samples := make(map[string]*profile.Sample)
+
+	for _, r := range records {
+		key := sampleKey(r)
+		if s, ok := samples[key]; ok {
+			s.Value[0]++
+			s.Value[1] += int64(r.wait)
+			s.NumLabel[goroutineIDLabel] = append(s.NumLabel[goroutineIDLabel], r.id)
+			continue
+		}
+		s := &profile.Sample{
+			Value: []int64{1, int64(r.wait)},
+			Label: map[string][]string{
+				goroutineStateLabel: {r.state},
+				goroutineWaitLabel:  {waitBucket(r.wait)},
+			},
+			NumLabel: map[string][]int64{goroutineIDLabel: {r.id}},
+		}
+		samples[key] = s
+		for _, frame := range r.frames {
+			loc, ok := locations[frame]
+			if !ok {
+				fnKey := [2]string{frame.function, frame.file}
+				fn, ok := functions[fnKey]
+				if !ok {
+					fn = &profile.Function{
+						ID:         uint64(len(p.Function) + 1),
+						Name:       frame.function,
+						SystemName: frame.function,
+						Filename:   frame.file,
+					}
+					functions[fnKey] = fn
+					p.Function = append(p.Function, fn)
+				}
+				loc = &profile.Location{
+					ID:   uint64(len(p.Location) + 1),
+					Line: []profile.Line{{Function: fn, Line: frame.line}},
+				}
+				locations[frame] = loc
+				p.Location = append(p.Location, loc)
+			}
+			s.Location = append(s.Location, loc)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	return p, p.CheckValid()
+}
+
+// sampleKey identifies the sample a goroutine is counted in.
+func sampleKey(r *goroutineRecord) string {
+	var b strings.Builder
+	b.WriteString(r.state)
+	b.WriteByte(0)
+	b.WriteString(waitBucket(r.wait))
+	for _, frame := range r.frames {
+		fmt.Fprintf(&b, "\x00%s\x00%s:%d", frame.function, frame.file, frame.line)
+	}
+	return b.String()
+}
+
+// scanGoroutineDump splits a dump into goroutines. Anything outside a
+// goroutine block, like a panic message, is ignored.
+func scanGoroutineDump(data []byte) ([]*goroutineRecord, error) {
+	var records []*goroutineRecord
+	var current *goroutineRecord
+	pendingFunc := "" // A function line waiting for its file:line line.
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if m := goroutineHeaderRE.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.ParseInt(m[1], 10, 64)
+			current = &goroutineRecord{id: id}
+			current.state, current.wait = parseGoroutineStatus(m[2])
+			records = append(records, current)
+			pendingFunc = ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case line == "":
+			current, pendingFunc = nil, ""
+		case strings.HasPrefix(line, "\t"):
+			m := frameFileRE.FindStringSubmatch(line)
+			if m == nil || pendingFunc == "" {
+				continue
+			}
+			lineNo, _ := strconv.ParseInt(m[2], 10, 64)
+			current.frames = append(current.frames, goroutineFrame{function: pendingFunc, file: m[1], line: lineNo})
+			pendingFunc = ""
+		case strings.HasPrefix(line, "created by "):
+			// The creator is the outermost frame of the goroutine's stack.
+			name := strings.TrimPrefix(line, "created by ")
+			if i := strings.Index(name, " in goroutine "); i >= 0 {
+				name = name[:i]
+			}
+			pendingFunc = name
+		default:
+			pendingFunc = trimFrameArgs(line)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// parseGoroutineStatus splits "chan receive, 5 minutes, locked to thread" into
+// the state and the wait duration. The runtime only reports waits of a minute or more.
+func parseGoroutineStatus(status string) (string, time.Duration) {
+	parts := strings.Split(status, ", ")
+	var wait time.Duration
+	for _, part := range parts[1:] {
+		if m := waitMinutesRE.FindStringSubmatch(part); m != nil {
+			minutes, _ := strconv.Atoi(m[1])
+			wait = time.Duration(minutes) * time.Minute
+		}
+	}
+	return parts[0], wait
+}
+
+// trimFrameArgs turns "main.(*T).run(0xc000010000, {0x1, 0x2})" into "main.(*T).run".
+func trimFrameArgs(line string) string {
+	if !strings.HasSuffix(line, ")") {
+		return line
+	}
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// waitBucket groups wait durations into coarse buckets for the wait label.
+func waitBucket(wait time.Duration) string {
+	switch {
+	case wait < time.Minute:
+		return "<1m"
+	case wait < 10*time.Minute:
+		return "1-10m"
+	case wait < time.Hour:
+		return "10-60m"
+	}
+	return ">1h"
+}
+
+// defaultPivotKey returns the label a profile is best split by when the user
+// did not pick one: goroutine dumps are grouped by state.
+func defaultPivotKey(p *profile.Profile) string {
+	if p == nil || len(p.SampleType) == 0 || p.SampleType[0].Type != "goroutine" {
+		return ""
+	}
+	for _, s := range p.Sample {
+		if _, ok := s.Label[goroutineStateLabel]; ok {
+			return goroutineStateLabel
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGoroutineDump = `panic: something went wrong
+
+goroutine 1 [running]:
+main.main()
+	/app/main.go:12 +0x25
+
+goroutine 7 [chan receive, 12 minutes]:
+main.(*Worker).run(0xc000010000, {0x1, 0x2})
+	/app/worker.go:40 +0x4a
+created by main.start in goroutine 1
+	/app/main.go:30 +0x3c
+
+goroutine 8 [IO wait]:
+internal/poll.runtime_pollWait(0x7f, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:351 +0x85
+main.(*Worker).run(0xc000010100, {0x3, 0x4})
+	/app/worker.go:44 +0x4a
+created by main.start in goroutine 1
+	/app/main.go:30 +0x3c
+exit status 2
+`
+
+func TestParseGoroutineDump(t *testing.T) {
+	if !isGoroutineDump([]byte(testGoroutineDump)) {
+		t.Fatal("dump not detected")
+	}
+	data, err := ParsePprofFile(strings.NewReader(testGoroutineDump), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	if len(data.Views) != 2 {
+		t.Fatalf("got %d views, want goroutine and wait", len(data.Views))
+	}
+	goroutines, wait := data.Views[0], data.Views[1]
+	if goroutines.TotalValue != 3 {
+		t.Errorf("goroutine count = %d, want 3", goroutines.TotalValue)
+	}
+	if wait.TotalValue != int64(12*time.Minute) {
+		t.Errorf("total wait = %d, want 12 minutes", wait.TotalValue)
+	}
+
+	run := findNode(goroutines, "main.(*Worker).run")
+	if run == nil || run.CumValue != 2 || run.FlatValue != 1 {
+		t.Fatalf("main.(*Worker).run = %+v, want cum 2 and flat 1", run)
+	}
+	if start := findNode(goroutines, "main.start"); start == nil || start.Out[run] != 2 {
+		t.Errorf("the creator should be the outermost frame of both workers")
+	}
+
+	states := make(map[string]int64)
+	for _, l := range CollectLabels(data.RawPprof, 0) {
+		if l.Key == goroutineStateLabel {
+			states[l.Value] = l.Total
+		}
+	}
+	if states["running"] != 1 || states["chan receive"] != 1 || states["IO wait"] != 1 {
+		t.Errorf("states = %v", states)
+	}
+	if got := defaultPivotKey(data.RawPprof); got != goroutineStateLabel {
+		t.Errorf("defaultPivotKey = %q, want %q", got, goroutineStateLabel)
+	}
+}
+
+func TestParseGoroutineDumpAggregatesStacks(t *testing.T) {
+	var dump strings.Builder
+	for id := 10; id < 13; id++ {
+		fmt.Fprintf(&dump, "goroutine %d [select, 3 minutes]:\nmain.serve()\n\t/app/server.go:20 +0x1f\n\n", id)
+	}
+	dump.WriteString("goroutine 13 [select]:\nmain.serve()\n\t/app/server.go:20 +0x1f\n")
+
+	p, err := ParseGoroutineDump([]byte(dump.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Sample) != 2 {
+		t.Fatalf("got %d samples, want one per wait bucket", len(p.Sample))
+	}
+	waiting := p.Sample[0]
+	if waiting.Value[0] != 3 || waiting.Value[1] != int64(9*time.Minute) {
+		t.Errorf("values = %v, want 3 goroutines waiting 9 minutes in total", waiting.Value)
+	}
+	if got := waiting.Label[goroutineWaitLabel]; len(got) != 1 || got[0] != "1-10m" {
+		t.Errorf("wait label = %v", got)
+	}
+	if ids := waiting.NumLabel[goroutineIDLabel]; !slices.Equal(ids, []int64{10, 11, 12}) {
+		t.Errorf("goroutine IDs = %v, want 10, 11 and 12", ids)
+	}
+	if ids := p.Sample[1].NumLabel[goroutineIDLabel]; !slices.Equal(ids, []int64{13}) {
+		t.Errorf("goroutine IDs = %v, want 13", ids)
+	}
+	for _, l := range CollectLabels(p, 0) {
+		if l.Key != goroutineStateLabel && l.Key != goroutineWaitLabel {
+			t.Errorf("unexpected label %s=%s in the labels panel", l.Key, l.Value)
+		}
+	}
+	// The IDs still find a goroutine's stack.
+	if f, _ := ParseFilters("tagfocus=goid=11"); len(f.Apply(p).Sample) != 1 {
+		t.Error("tagfocus=goid=11 should keep the sample of goroutine 11")
+	}
+}
+
+func TestParseGoroutineStatus(t *testing.T) {
+	state, wait := parseGoroutineStatus("select, 3 minutes, locked to thread")
+	if state != "select" || wait != 3*time.Minute {
+		t.Errorf("got %q, %v", state, wait)
+	}
+	if got := trimFrameArgs("main.Map[...](0x1, {0x2, 0x3})"); got != "main.Map[...]" {
+		t.Errorf("trimFrameArgs = %q", got)
+	}
+}
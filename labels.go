@@ -0,0 +1,197 @@
+// labels.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// LabelSummary is the total cost of all samples carrying one label value.
+type LabelSummary struct {
+	LabelMatch
+	Total int64
+}
+
+// sampleLabelValues returns the values of a label on a sample. Numeric labels
+// are formatted with their unit so they can be matched like string labels.
+func sampleLabelValues(s *profile.Sample, key string) []string {
+	if values, ok := s.Label[key]; ok {
+		return values
+	}
+	nums, ok := s.NumLabel[key]
+	if !ok {
+		return nil
+	}
+	units := s.NumUnit[key]
+	values := make([]string, len(nums))
+	for i, n := range nums {
+		if i < len(units) && units[i] != "" {
+			values[i] = fmt.Sprintf("%d %s", n, units[i])
+		} else {
+			values[i] = fmt.Sprintf("%d", n)
+		}
+	}
+	return values
+}
+
+// unlabelled is the pivot bucket for samples that do not carry the pivot label.
+const unlabelled = "(unlabelled)"
+
+// samplePivotValue returns the bucket a sample belongs to when pivoting on a label key.
+func samplePivotValue(s *profile.Sample, key string) string {
+	values := sampleLabelValues(s, key)
+	if len(values) == 0 {
+		return unlabelled
+	}
+	return strings.Join(values, ",")
+}
+
+// annotateLabelBreakdown records, for every function in every view, how its
+// cumulative value splits across the values of the pivot label.
+func annotateLabelBreakdown(data *ProfileData, key string) {
+	if data == nil || data.RawPprof == nil || key == "" {
+		return
+	}
+	for i, view := range data.Views {
+		for _, node := range view.Nodes {
+			node.LabelBreakdown = make(map[string]int64)
+		}
+		for _, s := range data.RawPprof.Sample {
+			val := s.Value[i]
+			if val == 0 {
+				continue
+			}
+			bucket := samplePivotValue(s, key)
+			seen := make(map[uint64]struct{})
+			for _, loc := range s.Location {
+				for _, line := range loc.Line {
+					if _, dup := seen[line.Function.ID]; dup {
+						continue
+					}
+					seen[line.Function.ID] = struct{}{}
+					if node, ok := view.Nodes[line.Function.ID]; ok {
+						node.LabelBreakdown[bucket] += val
+					}
+				}
+			}
+		}
+	}
+}
+
+// formatLabelBreakdown renders the largest label buckets of a function as
+// percentages of its cumulative value, e.g. "/users 60% · /orders 30% · +2 more".
+func formatLabelBreakdown(node *FuncNode, key string) string {
+	if len(node.LabelBreakdown) == 0 || node.CumValue == 0 {
+		return ""
+	}
+	buckets := make([]string, 0, len(node.LabelBreakdown))
+	for bucket := range node.LabelBreakdown {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		bi, bj := node.LabelBreakdown[buckets[i]], node.LabelBreakdown[buckets[j]]
+		if bi != bj {
+			return bi > bj
+		}
+		return buckets[i] < buckets[j]
+	})
+
+	const maxShown = 3
+	parts := make([]string, 0, maxShown+1)
+	for i, bucket := range buckets {
+		if i == maxShown {
+			parts = append(parts, fmt.Sprintf("+%d more", len(buckets)-maxShown))
+			break
+		}
+		percent := float64(node.LabelBreakdown[bucket]) / float64(node.CumValue) * 100
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", bucket, percent))
+	}
+	return key + ": " + strings.Join(parts, " · ")
+}
+
+// CollectLabels lists every label key and value found in the profile with the
+// total cost of its samples, grouped by key and ordered by cost within each key.
+func CollectLabels(p *profile.Profile, sampleIndex int) []LabelSummary {
+	if p == nil || sampleIndex >= len(p.SampleType) {
+		return nil
+	}
+	totals := make(map[LabelMatch]int64)
+	for _, s := range p.Sample {
+		val := s.Value[sampleIndex]
+		keys := make([]string, 0, len(s.Label)+len(s.NumLabel))
+		for key := range s.Label {
+			keys = append(keys, key)
+		}
+		for key := range s.NumLabel {
+			// Goroutine IDs are one-off values that would crowd out every other label.
+			if key != goroutineIDLabel {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			for _, value := range sampleLabelValues(s, key) {
+				totals[LabelMatch{Key: key, Value: value}] += val
+			}
+		}
+	}
+
+	summaries := make([]LabelSummary, 0, len(totals))
+	for match, total := range totals {
+		summaries = append(summaries, LabelSummary{LabelMatch: match, Total: total})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Key != summaries[j].Key {
+			return summaries[i].Key < summaries[j].Key
+		}
+		if summaries[i].Total != summaries[j].Total {
+			return summaries[i].Total > summaries[j].Total
+		}
+		return summaries[i].Value < summaries[j].Value
+	})
+	return summaries
+}
+
+// labelItem is a label value in the labels panel.
+type labelItem struct {
+	summary    LabelSummary
+	unit       string
+	totalValue int64
+	focused    bool
+	ignored    bool
+	pivoted    bool // The list and flame graph are split by this item's key.
+	styles     *Styles
+}
+
+func (i labelItem) Title() string {
+	title := i.summary.String()
+	switch {
+	case i.focused:
+		return i.styles.DiffPositive.Render("✔ " + title)
+	case i.ignored:
+		return i.styles.DiffNegative.Render("✘ " + title)
+	}
+	return title
+}
+
+func (i labelItem) Description() string {
+	percent := 0.0
+	if i.totalValue != 0 {
+		percent = float64(i.summary.Total) / float64(i.totalValue) * 100
+	}
+	desc := fmt.Sprintf("%s (%.1f%% of total)", formatValue(i.summary.Total, i.unit), percent)
+	switch {
+	case i.focused:
+		desc += " · focused"
+	case i.ignored:
+		desc += " · ignored"
+	}
+	if i.pivoted {
+		desc += " · grouped by " + i.summary.Key
+	}
+	return desc
+}
+
+func (i labelItem) FilterValue() string { return i.summary.String() }
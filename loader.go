@@ -0,0 +1,116 @@
+// loader.go
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// profileFormat is an input format pproftui can load.
+type profileFormat int
+
+const (
+	formatPprof         profileFormat = iota // pprof protobuf, gzipped or not.
+	formatGoroutineDump                      // /debug/pprof/goroutine?debug=2 or a panic traceback.
+	formatFolded                             // Collapsed stacks: "a;b;c 123".
+	formatPerfScript                         // Text output of `perf script`.
+	formatSpeedscope                         // speedscope JSON, sampled or evented.
+	formatGoTrace                            // runtime/trace execution trace.
+)
+
+func (f profileFormat) String() string {
+	return []string{"pprof", "goroutine dump", "folded stacks", "perf script", "speedscope", "execution trace"}[f]
+}
+
+// sniffLines is how many lines of a text format detection looks at.
+const sniffLines = 64
+
+// leadingLines returns up to n lines from the start of data, without their
+// line endings. The rest of data is never looked at.
+func leadingLines(data []byte, n int) [][]byte {
+	var lines [][]byte
+	for len(lines) < n && len(data) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		lines = append(lines, bytes.TrimRight(line, "\r"))
+		data = rest
+	}
+	return lines
+}
+
+// detectFormat sniffs the input format from its content. Text formats are
+// recognized by their first lines; anything else is assumed to be pprof.
+func detectFormat(data []byte) profileFormat {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		return formatPprof // gzip
+	}
+	switch {
+	case isGoTrace(data):
+		return formatGoTrace
+	case isSpeedscope(data):
+		return formatSpeedscope
+	case isGoroutineDump(data):
+		return formatGoroutineDump
+	case isPerfScript(data):
+		return formatPerfScript
+	case isFoldedStacks(data):
+		return formatFolded
+	}
+	return formatPprof
+}
+
+// parseProfile reads a profile in any supported format, converting text
+// formats into an equivalent pprof profile. Addresses without symbols are
+// resolved with the symbolizer, if one is given.
+func parseProfile(reader io.Reader, symbolizer *Symbolizer) (*profile.Profile, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var p *profile.Profile
+	switch detectFormat(data) {
+	case formatGoroutineDump:
+		p, err = ParseGoroutineDump(data)
+	case formatFolded:
+		p, err = ParseFoldedStacks(data)
+	case formatPerfScript:
+		p, err = ParsePerfScript(data)
+	case formatSpeedscope:
+		p, err = ParseSpeedscope(data)
+	case formatGoTrace:
+		p, err = ParseGoTrace(data)
+	default:
+		p, err = profile.ParseData(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if symbolizer != nil {
+		symbolizer.Symbolize(p)
+	}
+	labelUnsymbolized(p)
+	return p, nil
+}
+
+// exportProfile writes a profile to path. Files ending in .json are written
+// in speedscope's format, anything else as gzipped pprof, in which addresses
+// that could not be symbolized are left for pprof to resolve.
+func exportProfile(path string, p *profile.Profile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".json") {
+		err = WriteSpeedscope(f, p, filepath.Base(path))
+	} else {
+		err = stripUnsymbolized(p).Write(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
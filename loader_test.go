@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFoldedStacks = `main;run;parse 30
+main;run;parse;decode 20
+main;run 5
+main;(anonymous namespace)::flush buffers 7
+main;run;parse 10
+`
+
+func TestDetectFormat(t *testing.T) {
+	var buf strings.Builder
+	if err := newTestProfile(t).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		data string
+		want profileFormat
+	}{
+		{"gzipped pprof", buf.String(), formatPprof},
+		{"goroutine dump", testGoroutineDump, formatGoroutineDump},
+		{"folded stacks", testFoldedStacks, formatFolded},
+		{"long folded stacks", strings.Repeat(testFoldedStacks, 30), formatFolded},
+		{"long perf script", strings.Repeat(testPerfScript, 30), formatPerfScript},
+	} {
+		if got := detectFormat([]byte(tc.data)); got != tc.want {
+			t.Errorf("%s detected as %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseFoldedStacks(t *testing.T) {
+	data, err := ParsePprofFile(strings.NewReader(testFoldedStacks), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	view := data.Views[0]
+	if view.TotalValue != 72 {
+		t.Errorf("total = %d, want 72", view.TotalValue)
+	}
+	parse, decode, run := findNode(view, "parse"), findNode(view, "decode"), findNode(view, "run")
+	if parse.FlatValue != 40 || parse.CumValue != 60 {
+		t.Errorf("parse flat/cum = %d/%d, want 40/60", parse.FlatValue, parse.CumValue)
+	}
+	if parse.Out[decode] != 20 || run.Out[parse] != 60 {
+		t.Errorf("call edges run->parse=%d parse->decode=%d, want 60 and 20", run.Out[parse], parse.Out[decode])
+	}
+	if findNode(view, "(anonymous namespace)::flush buffers") == nil {
+		t.Error("frames with spaces should be kept whole")
+	}
+
+	root := BuildFlameGraph(data.RawPprof, 0, view.Unit, "")
+	if main := findNodeByName(root, "main"); main == nil || main.Value != 72 {
+		t.Errorf("flame graph root frame = %+v, want main with 72", main)
+	}
+}
+
+const testPerfScript = `# ========
+# captured on: Tue Oct  1 10:00:00 2024
+# ========
+#
+myproc  1234/1235 [002] 12345.678901:     250000 cpu-clock:pppH:
+	          4a1b25 main.work+0x25 (/usr/bin/myproc)
+	          4a1c10 main.main+0x30 (/usr/bin/myproc)
+
+myproc  1234/1236 [001] 12345.679151:     250000 cpu-clock:pppH:
+	ffffffff8100c10a native_safe_halt+0xa ([kernel.kallsyms])
+	    7f3a2b1c0d0e std::vector<int>::push_back(int const&)+0x1e (/usr/lib/libfoo.so)
+	          4a1b00 main.work+0x0 (/usr/bin/myproc)
+	          4a1c10 main.main+0x30 (/usr/bin/myproc)
+
+my worker  1234/1237 [003] 12345.679401:     500000 cpu-clock:pppH:
+	    7f3a2b1c0000 [unknown] (/usr/lib/libfoo.so)
+`
+
+func TestParsePerfScript(t *testing.T) {
+	if got := detectFormat([]byte(testPerfScript)); got != formatPerfScript {
+		t.Fatalf("detected as %s, want perf script", got)
+	}
+	data, err := ParsePprofFile(strings.NewReader(testPerfScript), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	if len(data.Views) != 2 || data.Views[1].Unit != "nanoseconds" {
+		t.Fatalf("want samples and cpu-clock views, got %d views", len(data.Views))
+	}
+	samples, clock := data.Views[0], data.Views[1]
+	if samples.TotalValue != 3 || clock.TotalValue != 1000000 {
+		t.Errorf("totals = %d samples, %d ns; want 3 and 1000000", samples.TotalValue, clock.TotalValue)
+	}
+	work := findNode(samples, "main.work")
+	if work == nil || work.FlatValue != 1 || work.CumValue != 2 {
+		t.Fatalf("main.work = %+v, want flat 1 and cum 2", work)
+	}
+	pushBack := findNode(samples, "std::vector<int>::push_back(int const&)")
+	if pushBack == nil || work.Out[pushBack] != 1 {
+		t.Errorf("expected main.work -> push_back edge, got %+v", pushBack)
+	}
+	if findNode(samples, "native_safe_halt") == nil || findNode(samples, "[unknown] (libfoo.so)") == nil {
+		t.Error("kernel and unsymbolized frames should become functions")
+	}
+
+	labels := make(map[string]int64)
+	for _, l := range CollectLabels(data.RawPprof, 0) {
+		labels[l.String()] = l.Total
+	}
+	if labels["comm=myproc"] != 2 || labels["comm=my worker"] != 1 || labels["pid=1234"] != 3 || labels["tid=1236"] != 1 {
+		t.Errorf("labels = %v", labels)
+	}
+}
+
+func TestParsePerfScriptKeepsEventsApart(t *testing.T) {
+	const script = `myproc  1234/1235 [002] 12345.678901:     2000 cycles:u:
+	          4a1b25 main.work+0x25 (/usr/bin/myproc)
+	          4a1c10 main.main+0x30 (/usr/bin/myproc)
+
+myproc  1234/1235 [002] 12345.678950:     3000 instructions:u:
+	          4a1b25 main.work+0x25 (/usr/bin/myproc)
+	          4a1c10 main.main+0x30 (/usr/bin/myproc)
+
+myproc  1234/1235 [002] 12345.679000:     1000 cycles:u:
+	          4a1c10 main.main+0x30 (/usr/bin/myproc)
+`
+	data, err := ParsePprofFile(strings.NewReader(script), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	var names []string
+	for _, st := range data.RawPprof.SampleType {
+		names = append(names, st.Type+"/"+st.Unit)
+	}
+	if got := strings.Join(names, " "); got != "samples/count cycles/count instructions/count" {
+		t.Fatalf("sample types = %s", got)
+	}
+	for i, want := range []int64{3, 3000, 3000} {
+		if got := data.Views[i].TotalValue; got != want {
+			t.Errorf("%s total = %d, want %d", data.Views[i].Name, got, want)
+		}
+	}
+	if work := findNode(data.Views[2], "main.work"); work == nil || work.FlatValue != 3000 {
+		t.Errorf("main.work instructions = %+v, want 3000", work)
+	}
+}
@@ -8,20 +8,104 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/pprof/profile"
 )
 
 func main() {
-	modulePath := flag.String("module-path", "", "Root module path of your project (e.g., github.com/user/repo) to highlight relevant code.")
+	modulePath := flag.String("module-path", "", "Comma-separated module paths of your project (e.g., github.com/user/repo) to highlight relevant code. Defaults to the modules of the go.work or go.mod in the current directory or above.")
 
 	liveURL := flag.String("live", "", "HTTP URL of a live pprof endpoint to poll (e.g., http://localhost:6060/debug/pprof/profile?seconds=5).")
 	refreshInterval := flag.Duration("refresh", 5*time.Second, "Refresh interval for live mode.")
 
+	merge := flag.Bool("merge", false, "Merge all given profiles (files, globs or URLs) into one aggregate profile instead of diffing them.")
+	series := flag.Bool("series", false, "Treat the given profiles (files, globs or URLs) as a time series, oldest first, and show each function's trend.")
+
+	binaryPath := flag.String("binary", "", "Path to the local ELF binary that produced the profile, used for the disassembly view and to symbolize profiles of stripped binaries.")
+	symbolsPath := flag.String("symbols", "", "Comma-separated ELF files or directories with unstripped binaries and shared libraries, matched to the profile's mappings by build ID, to symbolize addresses without function names.")
+
+	trimPath := flag.String("trim-path", "", "Path prefixes of the build machine to remove from source file names (e.g., /build/src), separated like $PATH.")
+	revision := flag.String("revision", "", "Git revision the profiled binary was built from; source is read from it with git show. Defaults to the vcs.revision in the profile's comments, if any.")
+	sourcePath := flag.String("source-path", "", "Directories to look for source files in when the recorded paths do not exist locally, separated like $PATH.")
+
+	focus := flag.String("focus", "", "Only keep samples whose stack has a frame matching this regexp.")
+	ignore := flag.String("ignore", "", "Drop samples whose stack has a frame matching this regexp.")
+	hide := flag.String("hide", "", "Remove frames matching this regexp from every stack, keeping the samples.")
+	show := flag.String("show", "", "Only keep frames matching this regexp in every stack.")
+	tagFocus := flag.String("tagfocus", "", "Only keep samples with one of these labels (comma-separated key=value pairs).")
+	tagIgnore := flag.String("tagignore", "", "Drop samples with any of these labels (comma-separated key=value pairs).")
+	nodeFraction := flag.Float64("nodefraction", 0, "Fold functions whose total is below this fraction of the profile into a single pruned entry (e.g., 0.005).")
+	edgeFraction := flag.Float64("edgefraction", 0, "Hide call edges whose weight is below this fraction of the profile (e.g., 0.001).")
+	pivotKey := flag.String("pivot", "", "Label key to split the function list and flame graph by (e.g., handler).")
+	exportPath := flag.String("export", "", "Write the loaded profile, after filters and node pruning (edge pruning is not applied), to this file and exit. Files ending in .json are written in speedscope format, anything else as gzipped pprof.")
+
 	flag.Parse()
 
+	var filters ProfileFilters
+	var err error
+	if filters.Focus, err = compileFilterRegexp("focus", *focus); err != nil {
+		log.Fatal(err)
+	}
+	if filters.Ignore, err = compileFilterRegexp("ignore", *ignore); err != nil {
+		log.Fatal(err)
+	}
+	if filters.Hide, err = compileFilterRegexp("hide", *hide); err != nil {
+		log.Fatal(err)
+	}
+	if filters.Show, err = compileFilterRegexp("show", *show); err != nil {
+		log.Fatal(err)
+	}
+	if filters.TagFocus, err = parseLabelMatches(*tagFocus); err != nil {
+		log.Fatal(err)
+	}
+	if filters.TagIgnore, err = parseLabelMatches(*tagIgnore); err != nil {
+		log.Fatal(err)
+	}
+	prune := PruneOptions{NodeFraction: *nodeFraction, EdgeFraction: *edgeFraction}
+	sources := NewSourceLocator(*trimPath, *sourcePath)
+	var modulePaths []string
+	if *modulePath != "" {
+		modulePaths = strings.Split(*modulePath, ",")
+	} else if wd, err := os.Getwd(); err == nil {
+		modulePaths = detectModulePaths(wd)
+	}
+	if *revision != "" {
+		if err := sources.SetRevision(*revision); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var binary *Binary
+	if *binaryPath != "" {
+		binary, err = OpenBinary(*binaryPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer binary.Close()
+	}
+
+	// The --binary doubles as the first binary to symbolize profiles with.
+	var symbolizer *Symbolizer
+	if binary != nil || *symbolsPath != "" {
+		var binaries []*Binary
+		if binary != nil {
+			binaries = append(binaries, binary)
+		}
+		var symbolPaths []string
+		if *symbolsPath != "" {
+			symbolPaths = strings.Split(*symbolsPath, ",")
+		}
+		symbolizer, err = NewSymbolizer(binaries, symbolPaths)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer symbolizer.Close()
+	}
+
 	if *liveURL != "" {
 		// In live mode, we initialize the model without data.
 		// The first fetch will happen as a command.
@@ -30,10 +114,14 @@ func main() {
 		m.isLiveMode = true
 		m.liveURL = *liveURL
 		m.refreshInterval = *refreshInterval
+		m.binary = binary
+		m.symbolizer = symbolizer
+		m.sources = sources
+		m.filters = filters
+		m.pivotKey = *pivotKey
+		m.prune = prune
 
-		if *modulePath != "" {
-			m.modulePath = *modulePath
-		}
+		m.modulePaths = modulePaths
 
 		p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseAllMotion())
 		if _, err := p.Run(); err != nil {
@@ -47,28 +135,92 @@ func main() {
 	if len(args) < 1 {
 		fmt.Println("Usage: pproftui [--module-path <your_module>] <profile_file_or_url>")
 		fmt.Println("       pproftui [--module-path <your_module>] <before_profile> <after_profile>")
+		fmt.Println("       pproftui [--module-path <your_module>] '<before_glob>' '<after_glob>'")
+		fmt.Println("       pproftui [--module-path <your_module>] --merge <profiles_or_globs>...")
+		fmt.Println("       pproftui [--module-path <your_module>] --series <profiles_or_globs>...")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
 	var sourceInfo string
 	var profileData *ProfileData
-	var err error
+	var seriesProfiles []*profile.Profile
 
-	if len(args) == 1 {
+	if *series {
+		// Time series mode
+		paths, globErr := expandProfileArgs(args)
+		if globErr != nil {
+			log.Fatal(globErr)
+		}
+		readers := make([]io.Reader, 0, len(paths))
+		for _, path := range paths {
+			reader, closer := getReaderForArg(path)
+			defer closer.Close()
+			readers = append(readers, reader)
+		}
+		seriesProfiles, err = ParsePprofSeries(readers, symbolizer)
+		if err == nil {
+			profileData, err = NewSeriesData(seriesProfiles)
+			sourceInfo = fmt.Sprintf("Series: %d profiles, oldest first (%s)", len(paths), strings.Join(args, " "))
+		}
+	} else if *merge {
+		// Merge mode
+		paths, globErr := expandProfileArgs(args)
+		if globErr != nil {
+			log.Fatal(globErr)
+		}
+		readers := make([]io.Reader, 0, len(paths))
+		for _, path := range paths {
+			reader, closer := getReaderForArg(path)
+			defer closer.Close()
+			readers = append(readers, reader)
+		}
+		profileData, err = MergePprofFiles(readers, symbolizer)
+		if err == nil {
+			sourceInfo = fmt.Sprintf("Merged: %d profiles, %s combined (%s)",
+				profileData.MergedCount,
+				time.Duration(profileData.DurationNanos).Round(time.Millisecond),
+				strings.Join(args, " "))
+		}
+	} else if len(args) == 1 {
 		// Single profile mode
 		sourceInfo = fmt.Sprintf("Source: %s", args[0])
 		reader, closer := getReaderForArg(args[0])
 		defer closer.Close()
-		profileData, err = ParsePprofFile(reader)
+		profileData, err = ParsePprofFile(reader, symbolizer)
 	} else if len(args) == 2 {
-		// Diff mode
-		sourceInfo = fmt.Sprintf("Diff: %s vs %s", args[0], args[1])
-		readerBefore, closerBefore := getReaderForArg(args[0])
-		defer closerBefore.Close()
-		readerAfter, closerAfter := getReaderForArg(args[1])
-		defer closerAfter.Close()
-		profileData, err = DiffPprofFiles(readerBefore, readerAfter)
+		beforePaths, globErr := expandProfileArgs(strings.Split(args[0], ","))
+		if globErr != nil {
+			log.Fatal(globErr)
+		}
+		afterPaths, globErr := expandProfileArgs(strings.Split(args[1], ","))
+		if globErr != nil {
+			log.Fatal(globErr)
+		}
+		if len(beforePaths) == 1 && len(afterPaths) == 1 {
+			// Diff mode
+			sourceInfo = fmt.Sprintf("Diff: %s vs %s", args[0], args[1])
+			readerBefore, closerBefore := getReaderForArg(beforePaths[0])
+			defer closerBefore.Close()
+			readerAfter, closerAfter := getReaderForArg(afterPaths[0])
+			defer closerAfter.Close()
+			profileData, err = DiffPprofFiles(readerBefore, readerAfter, symbolizer)
+		} else {
+			// Diff mode over repeated runs, comparing the mean of each set.
+			sourceInfo = fmt.Sprintf("Diff: %d runs (%s) vs %d runs (%s)", len(beforePaths), args[0], len(afterPaths), args[1])
+			var beforeReaders, afterReaders []io.Reader
+			for _, path := range beforePaths {
+				reader, closer := getReaderForArg(path)
+				defer closer.Close()
+				beforeReaders = append(beforeReaders, reader)
+			}
+			for _, path := range afterPaths {
+				reader, closer := getReaderForArg(path)
+				defer closer.Close()
+				afterReaders = append(afterReaders, reader)
+			}
+			profileData, err = DiffPprofSets(beforeReaders, afterReaders, symbolizer)
+		}
 	} else {
 		log.Fatal("Invalid number of arguments.")
 	}
@@ -76,18 +228,82 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if symbolizer != nil && symbolizer.Resolved() > 0 {
+		sourceInfo += fmt.Sprintf(" (symbolized %d locations)", symbolizer.Resolved())
+	}
 
-	if *modulePath != "" {
-		annotateProjectCode(profileData, *modulePath)
+	classifyCode(profileData, modulePaths)
+	if *revision == "" {
+		if rev := buildRevision(profileData.RawPprof); rev != "" {
+			// Binaries built from commits that are not checked out here just keep today's source.
+			_ = sources.SetRevision(rev)
+		}
+	}
+
+	if *pivotKey == "" && !*merge && !*series && len(args) == 1 {
+		*pivotKey = defaultPivotKey(profileData.RawPprof)
 	}
 
 	m := newModel(profileData, sourceInfo)
+	m.binary = binary
+	m.sources = sources
+	m.modulePaths = modulePaths
+	m.seriesProfiles = seriesProfiles
+	if len(seriesProfiles) > 0 {
+		m.sort = bySlope
+		m.resortAndSetList()
+	}
+	if !filters.IsEmpty() || *pivotKey != "" || prune.IsActive() {
+		if m.isDiffMode {
+			log.Fatal("Filters, pivots and pruning are not supported in diff mode.")
+		}
+		m.filters = filters
+		m.pivotKey = *pivotKey
+		m.prune = prune
+		m.reloadProfile()
+	}
+
+	if *exportPath != "" {
+		if m.isDiffMode {
+			log.Fatal("--export is not supported in diff mode.")
+		}
+		if m.lastError != nil {
+			log.Fatal(m.lastError)
+		}
+		if err := exportProfile(*exportPath, m.exportedProfile()); err != nil {
+			log.Fatalf("Failed to export profile: %v", err)
+		}
+		fmt.Println("Wrote", *exportPath)
+		return
+	}
+
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseAllMotion())
 	if _, err := p.Run(); err != nil {
 		log.Fatal("Error running program:", err)
 	}
 }
 
+// expandProfileArgs expands glob patterns in the arguments into the matching
+// files. URLs and plain paths are passed through unchanged.
+func expandProfileArgs(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") || !strings.ContainsAny(arg, "*?[") {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no profiles match %q", arg)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
 // getReaderForArg is a helper to avoid code duplication.
 func getReaderForArg(arg string) (io.Reader, io.Closer) {
 	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/pprof/profile"
 )
 
 type sortOrder int
@@ -21,13 +23,14 @@ const (
 	byFlat sortOrder = iota
 	byCum
 	byName
+	bySlope // Only offered for a series of profiles.
 )
 
 // Predefined layouts the user can cycle through.
 var layoutRatios = []float64{0.4, 0.6, 0.3} // 40/60, 60/40, 30/70
 
 func (s sortOrder) String() string {
-	return []string{"Self", "Total", "Name"}[s]
+	return []string{"Self", "Total", "Name", "Slope"}[s]
 }
 
 type viewMode int
@@ -36,6 +39,9 @@ const (
 	sourceView viewMode = iota
 	graphView
 	flameGraphView
+	disasmView
+	labelsView
+	modulesView
 )
 
 // pane tracks which UI pane is currently focused, used for keyboard navigation.
@@ -45,6 +51,8 @@ const (
 	listPane pane = iota
 	sourceCodePane
 	flameGraphPane
+	labelsPane
+	modulesPane
 )
 
 type tickMsg time.Time
@@ -62,26 +70,52 @@ func (e profileUpdateErr) Error() string { return e.err.Error() }
 type model struct {
 	// Core Data
 	profileData      *ProfileData
+	baseProfile      *profile.Profile   // The unfiltered profile that views are rebuilt from.
+	seriesProfiles   []*profile.Profile // The unfiltered profiles of a time series, oldest first.
+	filters          ProfileFilters
+	pivotKey         string // Label key that splits the list and flame graph, if any.
+	prune            PruneOptions
 	currentViewIndex int
 	mode             viewMode
 	sort             sortOrder
 	sourceInfo       string
 	isDiffMode       bool
-	showProjectOnly  bool
+	classFilter      CodeClass // Only list functions of this class, or classAll.
+	moduleFilter     string    // Only list functions of this module@version, when drilled into one.
+	attributed       bool      // Charge non-project cost to the deepest project frame of each stack.
+	hotLineIndex     int       // Position in the selected function's hot lines; -1 when not jumping.
 
 	// Live Mode State
 	isLiveMode      bool
 	isPaused        bool
 	liveURL         string
 	refreshInterval time.Duration
-	modulePath      string
+	modulePaths     []string // Modules whose functions are project code.
 	lastError       error
 
+	// Binary used for disassembly, if one was given with --binary.
+	binary *Binary
+	// Symbolizes live profiles of stripped binaries, if --binary or --symbols was given.
+	symbolizer *Symbolizer
+	// Finds the local copies of the profile's source files.
+	sources *SourceLocator
+
 	// UI components
 	mainList    list.Model
 	source      viewport.Model
 	callersList list.Model
 	calleesList list.Model
+	labelsList  list.Model
+	modulesList list.Model
+
+	// Filter prompt state
+	filterPrompt     textinput.Model
+	showFilterPrompt bool
+
+	// Save prompt state
+	savePrompt     textinput.Model
+	showSavePrompt bool
+	savedPath      string // Where the profile was last saved, shown in the header.
 
 	// Flamegraph state
 	flameGraphRoot     *FlameNode
@@ -89,6 +123,8 @@ type model struct {
 	flameGraphSelected *FlameNode // The user-selected node in the flame graph for keyboard nav
 	flameGraphHover    *FlameNode
 	flameGraphLayout   *[]FlameNodeRenderInfo
+	flameWidthBefore   bool // In diff mode, size flame frames by the before profile instead of the after one.
+	diffNorm           DiffNormalization
 	paneFocus          pane // Tracks which pane (list or flamegraph) has focus.
 
 	// General State
@@ -110,6 +146,8 @@ type listItem struct {
 	edgeValue   int64
 	contextNode *FuncNode
 	isCaller    bool
+	pivotKey    string
+	diffNorm    diffNormalizer // How diff deltas are normalized, in diff mode.
 }
 
 func newModel(data *ProfileData, sourceInfo string) model {
@@ -121,7 +159,8 @@ func newModel(data *ProfileData, sourceInfo string) model {
 		currentViewIndex:   0,
 		sourceInfo:         sourceInfo,
 		isDiffMode:         isDiff,
-		showProjectOnly:    false,
+		classFilter:        classAll,
+		hotLineIndex:       -1,
 		mode:               sourceView,
 		sort:               byFlat,
 		layoutIndex:        0,
@@ -130,6 +169,10 @@ func newModel(data *ProfileData, sourceInfo string) model {
 		mainList:           list.New(nil, list.NewDefaultDelegate(), 0, 0),
 		callersList:        list.New(nil, list.NewDefaultDelegate(), 0, 0),
 		calleesList:        list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		labelsList:         list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		modulesList:        list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		filterPrompt:       textinput.New(),
+		savePrompt:         textinput.New(),
 		source:             viewport.New(0, 0),
 		styles:             styles,
 		flameGraphLayout:   &[]FlameNodeRenderInfo{},
@@ -148,9 +191,20 @@ func newModel(data *ProfileData, sourceInfo string) model {
 	m.calleesList.Title = "Callees"
 	m.calleesList.SetShowHelp(false)
 	m.calleesList.SetShowStatusBar(false)
+	m.labelsList.Title = "Labels"
+	m.labelsList.SetShowHelp(false)
+	m.modulesList.Title = "Modules"
+	m.modulesList.SetShowHelp(false)
+	m.filterPrompt.Prompt = "filters> "
+	m.filterPrompt.Placeholder = "focus=regexp ignore=regexp hide=regexp show=regexp tagfocus=key=value tagignore=key=value"
+	m.savePrompt.Prompt = "save as> "
+	m.savePrompt.Placeholder = "profile.pb.gz"
 
 	// If data is provided initially (static mode), set the active view.
 	if data != nil {
+		if !isDiff {
+			m.baseProfile = data.RawPprof
+		}
 		m.setActiveView()
 	}
 
@@ -161,7 +215,7 @@ func (i listItem) Title() string {
 	if i.node.IsProjectCode {
 		return i.styles.ProjectCode.Render("★ " + i.node.Name)
 	}
-	return i.node.Name
+	return i.styles.classStyle(i.node.Class).Render(i.node.Name)
 }
 
 func (i listItem) Description() string {
@@ -185,6 +239,9 @@ func (i listItem) Description() string {
 	if i.contextNode != nil {
 		// Special handling for recursive calls, where the function appears in its own
 		// caller/callee list.
+		if i.node == i.contextNode && isDiff {
+			return fmt.Sprintf("This function is recursive; its self-calls changed by %s", formatDelta(i.edgeValue, i.unit, i.styles))
+		}
 		if i.node == i.contextNode {
 			edgeStr := formatValue(i.edgeValue, i.unit)
 			percent := formatPercent(i.edgeValue, i.node.CumValue)
@@ -213,12 +270,26 @@ func (i listItem) Description() string {
 
 	// Case 2: Diff mode
 	if isDiff {
-		flatStr := formatDiffImpact(i.node.FlatRatio, i.node.FlatDelta, i.unit, i.styles, i.TotalValue)
-		cumStr := formatDiffImpact(i.node.CumRatio, i.node.CumDelta, i.unit, i.styles, i.TotalValue)
+		n := i.diffNorm
+		flatStr := formatDiffImpact(n.ratio(i.node.FlatBefore, i.node.FlatAfter), n.delta(i.node.FlatBefore, i.node.FlatAfter), i.unit, i.styles, n, i.node.FlatStats)
+		cumStr := formatDiffImpact(n.ratio(i.node.CumBefore, i.node.CumAfter), n.delta(i.node.CumBefore, i.node.CumAfter), i.unit, i.styles, n, i.node.CumStats)
 		return fmt.Sprintf("own: %s | total: %s", flatStr, cumStr)
 	}
 
 	// Case 3: Main list descriptions
+	if len(i.node.Series) > 1 {
+		return i.describeCost() + " | " + formatSeriesTrend(i.node, i.unit)
+	}
+	if i.pivotKey != "" {
+		if breakdown := formatLabelBreakdown(i.node, i.pivotKey); breakdown != "" {
+			return i.describeCost() + " | " + breakdown
+		}
+	}
+	return i.describeCost()
+}
+
+// describeCost explains a function's own and total cost in terms of the current view.
+func (i listItem) describeCost() string {
 	ownVal := i.node.FlatValue
 	totalVal := i.node.CumValue
 	ownStr := formatValue(ownVal, i.unit)
@@ -251,6 +322,31 @@ func (i listItem) Description() string {
 		}
 		return fmt.Sprintf("%s; %s total including callees", base, totalStr)
 
+	case strings.HasPrefix(i.viewName, "goroutine"):
+		base := fmt.Sprintf("%s goroutines are stopped here", ownStr)
+		if isWorker {
+			return base
+		}
+		return fmt.Sprintf("%s; %s have it on their stack", base, totalStr)
+
+	case strings.Contains(i.viewName, "_blocking"), strings.Contains(i.viewName, "sched_latency"):
+		verb := "were blocked for"
+		if strings.Contains(i.viewName, "sched_latency") {
+			verb = "waited to be scheduled for"
+		}
+		base := fmt.Sprintf("goroutines stopped here %s %s", verb, ownStr)
+		if isWorker {
+			return base
+		}
+		return fmt.Sprintf("%s; %s including callees", base, totalStr)
+
+	case strings.HasPrefix(i.viewName, "wait "):
+		base := fmt.Sprintf("goroutines stopped here waited %s", ownStr)
+		if isWorker {
+			return base
+		}
+		return fmt.Sprintf("%s; %s for all goroutines with it on their stack", base, totalStr)
+
 	case strings.Contains(i.viewName, "cpu"), strings.Contains(i.viewName, "samples"):
 		// Check for recursion. A function is recursive if it's in its own 'Out' map.
 		_, isRecursive := i.node.Out[i.node]
@@ -304,6 +400,8 @@ func (m *model) applyPaneSizes() {
 	graphListHeight := paneHeight / 2
 	m.callersList.SetSize(rightPaneWidth, graphListHeight)
 	m.calleesList.SetSize(rightPaneWidth, paneHeight-graphListHeight)
+	m.labelsList.SetSize(rightPaneWidth, paneHeight)
+	m.modulesList.SetSize(rightPaneWidth, paneHeight)
 	m.helpView.Width = m.width - h
 	m.helpView.Height = paneHeight
 }
@@ -318,8 +416,11 @@ func (m *model) setActiveView() {
 	}
 	currentView := m.profileData.Views[m.currentViewIndex]
 	title := fmt.Sprintf("View: %s", currentView.Name)
-	if m.showProjectOnly {
-		title += " (Project Only)"
+	if m.classFilter != classAll {
+		title += fmt.Sprintf(" (%s only)", m.classFilter)
+	}
+	if m.moduleFilter != "" {
+		title += " (in " + m.moduleFilter + ")"
 	}
 	m.mainList.Title = title
 	// Invalidate flamegraph cache when view changes
@@ -341,27 +442,41 @@ func (m *model) resortAndSetList() {
 		nodes = append(nodes, node)
 	}
 
+	var norm diffNormalizer
+	if m.isDiffMode {
+		norm = newDiffNormalizer(m.diffNorm, currentView, m.profileData)
+	}
+
 	switch m.sort {
 	case byFlat:
 		if m.isDiffMode {
-			sort.Slice(nodes, func(i, j int) bool { return abs(nodes[i].FlatDelta) > abs(nodes[j].FlatDelta) })
+			sort.Slice(nodes, func(i, j int) bool {
+				return math.Abs(norm.delta(nodes[i].FlatBefore, nodes[i].FlatAfter)) > math.Abs(norm.delta(nodes[j].FlatBefore, nodes[j].FlatAfter))
+			})
 		} else {
 			sort.Slice(nodes, func(i, j int) bool { return nodes[i].FlatValue > nodes[j].FlatValue })
 		}
 	case byCum:
 		if m.isDiffMode {
-			sort.Slice(nodes, func(i, j int) bool { return abs(nodes[i].CumDelta) > abs(nodes[j].CumDelta) })
+			sort.Slice(nodes, func(i, j int) bool {
+				return math.Abs(norm.delta(nodes[i].CumBefore, nodes[i].CumAfter)) > math.Abs(norm.delta(nodes[j].CumBefore, nodes[j].CumAfter))
+			})
 		} else {
 			sort.Slice(nodes, func(i, j int) bool { return nodes[i].CumValue > nodes[j].CumValue })
 		}
 	case byName:
 		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	case bySlope:
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Slope > nodes[j].Slope })
 	}
 
 	items := make([]list.Item, 0, len(nodes))
 	for _, node := range nodes {
-		if m.showProjectOnly && !node.IsProjectCode {
-			continue // Skip if we're in project-only mode and this node isn't project code.
+		if m.classFilter != classAll && node.Class != m.classFilter {
+			continue // Skip functions of the classes the filter hides.
+		}
+		if m.moduleFilter != "" && m.nodeModule(node) != m.moduleFilter {
+			continue
 		}
 		items = append(items, listItem{
 			node:       node,
@@ -369,6 +484,8 @@ func (m *model) resortAndSetList() {
 			viewName:   currentView.Name,
 			styles:     &m.styles,
 			TotalValue: currentView.TotalValue,
+			pivotKey:   m.pivotKey,
+			diffNorm:   norm,
 		})
 	}
 
@@ -395,17 +512,54 @@ func (m *model) updateChildPanes() {
 	}
 
 	// Update Source View
-	content := getHighlightedSource(selected.node.FileName, selected.node.StartLine)
+	m.hotLineIndex = -1
+	if m.mode == disasmView {
+		m.showDisassembly(selected.node)
+	} else {
+		m.showSourceAt(selected.node, selected.node.StartLine)
+	}
+
+	// Update Graph View (Callers/Callees)
+	m.updateGraphLists(selected.node)
+}
+
+// showSourceAt renders the source of a function and centers the view on the given line.
+func (m *model) showSourceAt(node *FuncNode, line int) {
+	unit := m.profileData.Views[m.currentViewIndex].Unit
+	content := getHighlightedSource(m.sources, node.FileName, node.Name, line, node.Lines, unit)
 	m.source.SetContent(content)
 	halfViewportHeight := m.source.Height / 2
-	scrollPos := selected.node.StartLine - halfViewportHeight
+	scrollPos := line - halfViewportHeight
 	if scrollPos < 0 {
 		scrollPos = 0
 	}
 	m.source.SetYOffset(scrollPos)
+}
 
-	// Update Graph View (Callers/Callees)
-	m.updateGraphLists(selected.node)
+// showDisassembly renders the annotated instructions of a function into the source pane.
+func (m *model) showDisassembly(node *FuncNode) {
+	unit := m.profileData.Views[m.currentViewIndex].Unit
+	m.source.SetContent(getDisassembly(m.binary, m.profileData.RawPprof, m.currentViewIndex, node.Name, unit))
+	m.source.GotoTop()
+}
+
+// jumpToHotLine moves the source view to the next (step > 0) or previous (step < 0)
+// hottest line of the selected function, wrapping around at either end.
+func (m *model) jumpToHotLine(step int) {
+	selected, ok := m.mainList.SelectedItem().(listItem)
+	if !ok {
+		return
+	}
+	hotLines := selected.node.HotLines()
+	if len(hotLines) == 0 {
+		return
+	}
+	if m.hotLineIndex < 0 && step < 0 {
+		m.hotLineIndex = len(hotLines) - 1
+	} else {
+		m.hotLineIndex = (m.hotLineIndex + step + len(hotLines)) % len(hotLines)
+	}
+	m.showSourceAt(selected.node, hotLines[m.hotLineIndex])
 }
 
 // updateGraphLists populates the caller and callee lists.
@@ -431,7 +585,7 @@ func (m *model) updateGraphLists(selectedNode *FuncNode) {
 	}
 	// Sort callers by the edge weight (most impactful callers first)
 	sort.Slice(callerItems, func(i, j int) bool {
-		return callerItems[i].(listItem).edgeValue > callerItems[j].(listItem).edgeValue
+		return m.edgeRank(callerItems[i].(listItem).edgeValue) > m.edgeRank(callerItems[j].(listItem).edgeValue)
 	})
 	m.callersList.SetItems(callerItems)
 
@@ -451,16 +605,25 @@ func (m *model) updateGraphLists(selectedNode *FuncNode) {
 	}
 	// Sort callees by the edge weight (most expensive calls first)
 	sort.Slice(calleeItems, func(i, j int) bool {
-		return calleeItems[i].(listItem).edgeValue > calleeItems[j].(listItem).edgeValue
+		return m.edgeRank(calleeItems[i].(listItem).edgeValue) > m.edgeRank(calleeItems[j].(listItem).edgeValue)
 	})
 	m.calleesList.SetItems(calleeItems)
 }
 
+// edgeRank is the value caller/callee lists are sorted by. In diff mode edges
+// hold deltas, so the largest change in either direction comes first.
+func (m *model) edgeRank(edgeValue int64) int64 {
+	if m.isDiffMode {
+		return abs(edgeValue)
+	}
+	return edgeValue
+}
+
 func (m model) Init() tea.Cmd {
 	if m.isLiveMode {
 		// For live mode, we start with an initial fetch and then start the ticker.
 		return tea.Batch(
-			fetchProfileCmd(m.liveURL, m.modulePath),
+			fetchProfileCmd(m.liveURL, m.modulePaths, m.symbolizer),
 			tickerCmd(m.refreshInterval),
 		)
 	}
@@ -482,6 +645,57 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		return m, tea.Batch(cmds...)
 	}
+	// While the filter prompt is open, it receives every keystroke.
+	if m.showFilterPrompt {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc":
+				m.showFilterPrompt = false
+				m.filterPrompt.Blur()
+				return m, nil
+			case "enter":
+				m.showFilterPrompt = false
+				m.filterPrompt.Blur()
+				filters, err := ParseFilters(m.filterPrompt.Value())
+				if err != nil {
+					m.lastError = err
+					return m, nil
+				}
+				m.filters = filters
+				m.reloadProfile()
+				return m, nil
+			}
+		}
+		m.filterPrompt, cmd = m.filterPrompt.Update(msg)
+		return m, cmd
+	}
+	// Likewise for the save prompt.
+	if m.showSavePrompt {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc":
+				m.showSavePrompt = false
+				m.savePrompt.Blur()
+				return m, nil
+			case "enter":
+				m.showSavePrompt = false
+				m.savePrompt.Blur()
+				path := strings.TrimSpace(m.savePrompt.Value())
+				if path == "" {
+					return m, nil
+				}
+				if err := exportProfile(path, m.exportedProfile()); err != nil {
+					m.lastError = fmt.Errorf("saving profile: %w", err)
+					return m, nil
+				}
+				m.lastError = nil
+				m.savedPath = path
+				return m, nil
+			}
+		}
+		m.savePrompt, cmd = m.savePrompt.Update(msg)
+		return m, cmd
+	}
 	// If the list is filtering, we only want to pass keystrokes to it.
 	// We don't want our other keybindings (t, c, q) to be active.
 	if m.mainList.FilterState() == list.Filtering {
@@ -510,7 +724,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case tickMsg:
 		if m.isLiveMode && !m.isPaused {
-			cmds = append(cmds, fetchProfileCmd(m.liveURL, m.modulePath))
+			cmds = append(cmds, fetchProfileCmd(m.liveURL, m.modulePaths, m.symbolizer))
 		}
 		// Always return the ticker command to keep it going even if paused
 		cmds = append(cmds, tickerCmd(m.refreshInterval))
@@ -518,38 +732,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case profileUpdateMsg:
 		m.lastError = nil // Clear any previous error
-
-		// Remember what function is currently selected to preserve state
-		var selectedFuncName string
-		if selected, ok := m.mainList.SelectedItem().(listItem); ok {
-			selectedFuncName = selected.node.Name
-		}
-
-		m.profileData = msg.data
-
-		// If this is the first data load, set up the view
-		if m.mainList.Items() == nil {
-			m.setActiveView()
-		} else { // Otherwise, just refresh the list content
-			m.resortAndSetList()
-		}
-
-		// Restore selection if possible
-		if selectedFuncName != "" {
-			for i, item := range m.mainList.Items() {
-				if li, ok := item.(listItem); ok && li.node.Name == selectedFuncName {
-					m.mainList.Select(i)
-					break
-				}
-			}
-		}
-
-		// Refresh all dependent panes
-		m.updateChildPanes()
-		if m.mode == flameGraphView {
-			m.rebuildFlameGraph()
+		m.baseProfile = msg.data.RawPprof
+		if m.filters.IsEmpty() && !m.prune.IsActive() && !m.attributed {
+			m.setProfileData(msg.data)
+		} else {
+			m.reloadProfile()
 		}
-
 		return m, nil
 
 	case profileUpdateErr:
@@ -614,6 +802,73 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// The labels panel takes over selection keys while it has focus.
+		if m.mode == labelsView && m.paneFocus == labelsPane && m.labelsList.FilterState() != list.Filtering {
+			switch msg.String() {
+			case "tab":
+				m.paneFocus = listPane
+				return m, nil
+			case "enter", "x":
+				if item, ok := m.labelsList.SelectedItem().(labelItem); ok {
+					if msg.String() == "enter" {
+						m.filters.TagFocus = toggleLabel(m.filters.TagFocus, item.summary.LabelMatch)
+					} else {
+						m.filters.TagIgnore = toggleLabel(m.filters.TagIgnore, item.summary.LabelMatch)
+					}
+					m.reloadProfile()
+				}
+				return m, nil
+			case "g":
+				if item, ok := m.labelsList.SelectedItem().(labelItem); ok {
+					if m.pivotKey == item.summary.Key {
+						m.pivotKey = ""
+					} else {
+						m.pivotKey = item.summary.Key
+					}
+					m.reloadProfile()
+				}
+				return m, nil
+			case "backspace":
+				m.filters.TagFocus = nil
+				m.filters.TagIgnore = nil
+				m.reloadProfile()
+				return m, nil
+			case "ctrl+c", "q":
+				return m, tea.Quit
+			}
+			m.labelsList, cmd = m.labelsList.Update(msg)
+			return m, cmd
+		}
+
+		// The modules panel works like the labels panel.
+		if m.mode == modulesView && m.paneFocus == modulesPane && m.modulesList.FilterState() != list.Filtering {
+			switch msg.String() {
+			case "tab":
+				m.paneFocus = listPane
+				return m, nil
+			case "enter":
+				if item, ok := m.modulesList.SelectedItem().(moduleItem); ok {
+					if m.moduleFilter == item.cost.String() {
+						m.moduleFilter = ""
+					} else {
+						m.moduleFilter = item.cost.String()
+					}
+					m.setActiveView()
+					m.updateModulesList()
+				}
+				return m, nil
+			case "backspace":
+				m.moduleFilter = ""
+				m.setActiveView()
+				m.updateModulesList()
+				return m, nil
+			case "ctrl+c", "q":
+				return m, tea.Quit
+			}
+			m.modulesList, cmd = m.modulesList.Update(msg)
+			return m, cmd
+		}
+
 		if m.mainList.FilterState() != list.Filtering {
 			switch msg.String() {
 			case " ": // Spacebar to pause/resume
@@ -642,7 +897,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Quit
 
 			case "tab": // Handle tab for focus switching
-				if m.mode == sourceView {
+				if m.mode == sourceView || m.mode == disasmView {
 					switch m.paneFocus {
 					case sourceCodePane:
 						m.paneFocus = listPane
@@ -652,6 +907,15 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
+				if m.mode == labelsView {
+					m.paneFocus = labelsPane
+					return m, nil
+				}
+				if m.mode == modulesView {
+					m.paneFocus = modulesPane
+					return m, nil
+				}
+
 				if m.mode == flameGraphView {
 					// This will only be reached if paneFocus is listPane
 					m.paneFocus = flameGraphPane
@@ -673,27 +937,114 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if m.mode == flameGraphView {
 						m.rebuildFlameGraph()
 					}
+					if m.mode == labelsView {
+						m.updateLabelsList()
+					}
+					if m.mode == modulesView {
+						m.updateModulesList()
+					}
 				}
 				return m, nil
 			case "c":
-				if m.isDiffMode {
-					return m, nil
-				}
 				if m.mode == sourceView {
 					m.mode = graphView
 				} else {
 					m.mode = sourceView
 				}
 				m.paneFocus = listPane // Reset focus on mode change
+				m.updateChildPanes()
+				return m, nil
+			case "d":
+				if m.isDiffMode || m.binary == nil {
+					return m, nil
+				}
+				if m.mode == disasmView {
+					m.mode = sourceView
+				} else {
+					m.mode = disasmView
+				}
+				m.paneFocus = listPane
+				m.updateChildPanes()
+				return m, nil
+			case "F":
+				if m.baseProfile == nil {
+					return m, nil
+				}
+				m.filterPrompt.SetValue(m.filters.String())
+				m.filterPrompt.CursorEnd()
+				m.showFilterPrompt = true
+				return m, m.filterPrompt.Focus()
+			case "S":
+				if m.isDiffMode || m.profileData == nil || m.profileData.RawPprof == nil {
+					return m, nil
+				}
+				m.savePrompt.SetValue(time.Now().Format("pproftui-20060102-150405.pb.gz"))
+				m.savePrompt.CursorEnd()
+				m.showSavePrompt = true
+				return m, m.savePrompt.Focus()
+			case "[", "]", "{", "}":
+				if m.baseProfile == nil {
+					return m, nil
+				}
+				switch msg.String() {
+				case "[":
+					m.prune.NodeFraction = stepFraction(m.prune.NodeFraction, -1)
+				case "]":
+					m.prune.NodeFraction = stepFraction(m.prune.NodeFraction, 1)
+				case "{":
+					m.prune.EdgeFraction = stepFraction(m.prune.EdgeFraction, -1)
+				case "}":
+					m.prune.EdgeFraction = stepFraction(m.prune.EdgeFraction, 1)
+				}
+				m.reloadProfile()
+				return m, nil
+			case "L":
+				if m.baseProfile == nil {
+					return m, nil
+				}
+				if m.mode == labelsView {
+					m.mode = sourceView
+				} else {
+					m.mode = labelsView
+					m.updateLabelsList()
+				}
+				m.paneFocus = listPane
+				m.updateChildPanes()
+				return m, nil
+			case "M":
+				if m.isDiffMode || m.profileData == nil {
+					return m, nil
+				}
+				if m.mode == modulesView {
+					m.mode = sourceView
+				} else {
+					m.mode = modulesView
+					m.updateModulesList()
+				}
+				m.paneFocus = listPane
+				m.updateChildPanes()
 				return m, nil
 			case "s":
-				m.sort = (m.sort + 1) % 3 // Cycle through the 3 sort orders
+				if len(m.seriesProfiles) > 0 {
+					m.sort = (m.sort + 1) % 4 // Growth over the series is a 4th sort order
+				} else {
+					m.sort = (m.sort + 1) % 3 // Cycle through the 3 sort orders
+				}
 				m.resortAndSetList()
 				return m, nil
-			case "f":
+			case "%":
 				if m.isDiffMode {
+					m.diffNorm = nextDiffNormalization(m.diffNorm, m.profileData)
+					m.resortAndSetList()
 					return m, nil
 				}
+			case "w":
+				if m.isDiffMode && m.mode == flameGraphView && m.flameGraphRoot != nil {
+					m.flameWidthBefore = !m.flameWidthBefore
+					setFlameWidthBasis(m.flameGraphRoot, m.flameWidthBefore)
+					return m, nil
+				}
+			case "f":
 				if m.mode == flameGraphView {
 					m.mode = sourceView // Toggle back
 					m.flameGraphRoot = nil
@@ -740,12 +1091,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.syncListToFlameGraphSelection()
 					return m, nil
 				}
+			case "n", "N":
+				if m.mode == sourceView {
+					if msg.String() == "n" {
+						m.jumpToHotLine(1)
+					} else {
+						m.jumpToHotLine(-1)
+					}
+					return m, nil
+				}
 			case "r":
 				m.layoutIndex = (m.layoutIndex + 1) % len(layoutRatios)
 				m.applyPaneSizes()
 				return m, nil
+			case "a":
+				if m.baseProfile == nil && len(m.seriesProfiles) == 0 {
+					return m, nil
+				}
+				m.attributed = !m.attributed
+				m.reloadProfile()
+				return m, nil
 			case "p":
-				m.showProjectOnly = !m.showProjectOnly
+				m.classFilter = nextClassFilter(m.classFilter)
 				m.setActiveView() // This invalidates the old list and flamegraph
 				if m.mode == flameGraphView {
 					m.rebuildFlameGraph()
@@ -755,7 +1122,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	if m.mode == sourceView && m.paneFocus == sourceCodePane {
+	if (m.mode == sourceView || m.mode == disasmView) && m.paneFocus == sourceCodePane {
 		m.source, _ = m.source.Update(msg)
 		return m, tea.Batch(cmds...)
 	}
@@ -775,7 +1142,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.mode == graphView {
 		m.callersList, _ = m.callersList.Update(msg)
 		m.calleesList, _ = m.calleesList.Update(msg)
-	} else if m.mode == sourceView {
+	} else if m.mode == sourceView || m.mode == disasmView {
 		m.source, _ = m.source.Update(msg)
 	}
 
@@ -885,7 +1252,11 @@ func (m *model) navigateFlameGraph(direction string) {
 
 func (m *model) rebuildFlameGraph() {
 	currentView := m.profileData.Views[m.currentViewIndex]
-	m.flameGraphRoot = BuildFlameGraph(m.profileData.RawPprof, m.currentViewIndex, currentView.Unit)
+	if m.isDiffMode {
+		m.flameGraphRoot = BuildDiffFlameGraph(m.profileData.BasePprof, m.profileData.RawPprof, currentView.SampleType, m.flameWidthBefore)
+	} else {
+		m.flameGraphRoot = BuildFlameGraph(m.profileData.RawPprof, m.currentViewIndex, currentView.Unit, m.pivotKey)
+	}
 	// Reset focus to the root of the new graph
 	m.flameGraphFocus = m.flameGraphRoot
 	// If the graph pane has focus, reset selection to the new root as well
@@ -894,6 +1265,180 @@ func (m *model) rebuildFlameGraph() {
 	}
 }
 
+// setProfileData swaps in freshly built profile data while preserving the
+// selected function and the state of the right-hand pane.
+func (m *model) setProfileData(data *ProfileData) {
+	// Remember what function is currently selected to preserve state
+	var selectedFuncName string
+	if selected, ok := m.mainList.SelectedItem().(listItem); ok {
+		selectedFuncName = selected.node.Name
+	}
+
+	m.profileData = data
+	if m.pivotKey != "" {
+		annotateLabelBreakdown(data, m.pivotKey)
+	}
+	if m.ready {
+		// Filters and pivots are listed in the header, which may change its height.
+		m.applyPaneSizes()
+	}
+
+	// If this is the first data load, set up the view
+	if m.mainList.Items() == nil {
+		m.setActiveView()
+	} else { // Otherwise, just refresh the list content
+		m.resortAndSetList()
+	}
+
+	// Restore selection if possible
+	if selectedFuncName != "" {
+		for i, item := range m.mainList.Items() {
+			if li, ok := item.(listItem); ok && li.node.Name == selectedFuncName {
+				m.mainList.Select(i)
+				break
+			}
+		}
+	}
+
+	// Refresh all dependent panes
+	m.updateChildPanes()
+	if m.mode == flameGraphView {
+		m.rebuildFlameGraph()
+	}
+	if m.mode == labelsView {
+		m.updateLabelsList()
+	}
+	if m.mode == modulesView {
+		m.updateModulesList()
+	}
+}
+
+// transformProfile applies the filters and, in the project-attributed view,
+// charges every sample to its deepest project frame.
+func (m *model) transformProfile(p *profile.Profile) *profile.Profile {
+	p = m.filters.Apply(p)
+	if m.attributed {
+		p = attributeToProject(p, m.modulePaths)
+	}
+	return p
+}
+
+// exportedProfile is the profile behind the current views, as saved by `S`
+// and --export: filtered, attributed and merged like the views, with the
+// functions pruned from the current view folded together. The edge threshold
+// is not applied, since samples cannot leave out a single call; see
+// pruneProfile.
+func (m model) exportedProfile() *profile.Profile {
+	p := m.profileData.RawPprof
+	if m.prune.NodeFraction > 0 && m.currentViewIndex < len(m.profileData.Views) {
+		p = pruneProfile(p, m.currentViewIndex, m.profileData.Views[m.currentViewIndex])
+	}
+	return p
+}
+
+// savedNote explains what a saved profile leaves out of the current view.
+func (m model) savedNote() string {
+	if m.prune.EdgeFraction > 0 {
+		return " (edge pruning is not saved)"
+	}
+	return ""
+}
+
+// reloadProfile rebuilds every view from the base profile with the current filters applied.
+func (m *model) reloadProfile() {
+	var data *ProfileData
+	var err error
+	switch {
+	case len(m.seriesProfiles) > 0:
+		filtered := make([]*profile.Profile, len(m.seriesProfiles))
+		for i, p := range m.seriesProfiles {
+			filtered[i] = m.transformProfile(p)
+		}
+		data, err = NewSeriesData(filtered)
+	case m.baseProfile != nil:
+		data, err = NewProfileData(m.transformProfile(m.baseProfile))
+	default:
+		return
+	}
+	if err != nil {
+		m.lastError = err
+		return
+	}
+	m.lastError = nil
+	pruneProfileData(data, m.prune)
+	classifyCode(data, m.modulePaths)
+	m.setProfileData(data)
+}
+
+// updateLabelsList fills the labels panel from the unfiltered profile, so that
+// values excluded by the current filters can still be selected.
+func (m *model) updateLabelsList() {
+	if m.baseProfile == nil || m.currentViewIndex >= len(m.baseProfile.SampleType) {
+		m.labelsList.SetItems(nil)
+		return
+	}
+	unit := m.baseProfile.SampleType[m.currentViewIndex].Unit
+	var total int64
+	for _, s := range m.baseProfile.Sample {
+		total += s.Value[m.currentViewIndex]
+	}
+
+	summaries := CollectLabels(m.baseProfile, m.currentViewIndex)
+	items := make([]list.Item, 0, len(summaries))
+	for _, summary := range summaries {
+		items = append(items, labelItem{
+			summary:    summary,
+			unit:       unit,
+			totalValue: total,
+			focused:    slices.Contains(m.filters.TagFocus, summary.LabelMatch),
+			ignored:    slices.Contains(m.filters.TagIgnore, summary.LabelMatch),
+			pivoted:    summary.Key == m.pivotKey,
+			styles:     &m.styles,
+		})
+	}
+	if len(items) == 0 {
+		m.labelsList.Title = "Labels (none in this profile)"
+	} else {
+		m.labelsList.Title = "Labels"
+	}
+	m.labelsList.SetItems(items)
+}
+
+// nodeModule returns the module@version a function of the current profile belongs to.
+func (m *model) nodeModule(node *FuncNode) string {
+	var requires map[string]string
+	if m.sources != nil {
+		requires = m.sources.Requires
+	}
+	module, version := funcModule(node, m.modulePaths, requires)
+	return ModuleCost{Module: module, Version: version}.String()
+}
+
+// updateModulesList fills the modules panel from the current, filtered view.
+func (m *model) updateModulesList() {
+	if m.profileData == nil || m.currentViewIndex >= len(m.profileData.Views) {
+		m.modulesList.SetItems(nil)
+		return
+	}
+	view := m.profileData.Views[m.currentViewIndex]
+	var requires map[string]string
+	if m.sources != nil {
+		requires = m.sources.Requires
+	}
+	costs := CollectModuleCosts(m.profileData.RawPprof, m.currentViewIndex, view, m.modulePaths, requires)
+	items := make([]list.Item, 0, len(costs))
+	for _, cost := range costs {
+		items = append(items, moduleItem{
+			cost:       cost,
+			unit:       view.Unit,
+			totalValue: view.TotalValue,
+			drilled:    cost.String() == m.moduleFilter,
+			styles:     &m.styles,
+		})
+	}
+	m.modulesList.SetItems(items)
+}
+
 // syncListToFlameGraphSelection finds the item in the mainList that corresponds
 // to the currently selected flame graph node and selects it.
 func (m *model) syncListToFlameGraphSelection() {
@@ -925,15 +1470,15 @@ func (m model) View() string {
 	sourceStyle := m.styles.Source
 	activeBorderColor := lipgloss.Color("82") // A bright cyan for focus
 
-	if m.mode == flameGraphView {
+	if m.mode == flameGraphView || m.mode == labelsView || m.mode == modulesView {
 		if m.paneFocus == listPane {
 			listStyle = listStyle.BorderForeground(activeBorderColor)
-		} else { // flameGraphPane has focus
+		} else { // flameGraphPane, labelsPane or modulesPane has focus
 			sourceStyle = sourceStyle.BorderForeground(activeBorderColor)
 		}
 	}
 
-	if m.mode == sourceView {
+	if m.mode == sourceView || m.mode == disasmView {
 		if m.paneFocus == sourceCodePane {
 			rightPane = sourceStyle.BorderForeground(activeBorderColor).Render(m.source.View())
 		} else {
@@ -942,6 +1487,10 @@ func (m model) View() string {
 		}
 	} else if m.mode == graphView {
 		rightPane = lipgloss.JoinVertical(lipgloss.Left, m.callersList.View(), m.calleesList.View())
+	} else if m.mode == labelsView {
+		rightPane = sourceStyle.Render(m.labelsList.View())
+	} else if m.mode == modulesView {
+		rightPane = sourceStyle.Render(m.modulesList.View())
 	} else {
 		var listSelectedNode *FlameNode
 		if selected, ok := m.mainList.SelectedItem().(listItem); ok {
@@ -973,17 +1522,34 @@ func (m model) View() string {
 
 		// Prepare hover details string
 		var hoverDetails string
-		if m.flameGraphHover != nil {
+		detailsNode, detailsPrefix := m.flameGraphHover, "Hover"
+		if detailsNode == nil && m.isDiffMode && m.paneFocus == flameGraphPane {
+			// Diff frames only show a percentage, so also detail the keyboard selection.
+			detailsNode, detailsPrefix = m.flameGraphSelected, "Selected"
+		}
+		if detailsNode != nil {
 			currentView := m.profileData.Views[m.currentViewIndex]
-			percentOfTotal := 0.0
-			if totalValue > 0 {
-				percentOfTotal = (float64(m.flameGraphHover.Value) / float64(totalValue)) * 100
+			if detailsNode.Diff {
+				hoverDetails = fmt.Sprintf("%s: %s | before %s | after %s | %s (%s)",
+					detailsPrefix,
+					detailsNode.Name,
+					formatValue(detailsNode.BeforeValue, currentView.Unit),
+					formatValue(detailsNode.AfterValue, currentView.Unit),
+					formatSignedValue(detailsNode.AfterValue-detailsNode.BeforeValue, currentView.Unit),
+					formatFlameDelta(detailsNode),
+				)
+			} else {
+				percentOfTotal := 0.0
+				if totalValue > 0 {
+					percentOfTotal = (float64(detailsNode.Value) / float64(totalValue)) * 100
+				}
+				hoverDetails = fmt.Sprintf("%s: %s | %s | %.1f%% of total",
+					detailsPrefix,
+					detailsNode.Name,
+					formatValue(detailsNode.Value, currentView.Unit),
+					percentOfTotal,
+				)
 			}
-			hoverDetails = fmt.Sprintf("Hover: %s | %s | %.1f%% of total",
-				m.flameGraphHover.Name,
-				formatValue(m.flameGraphHover.Value, currentView.Unit),
-				percentOfTotal,
-			)
 		}
 
 		// Combine graph with an optional details bar at the bottom
@@ -1016,8 +1582,19 @@ func (m model) View() string {
 	panes := lipgloss.JoinHorizontal(lipgloss.Top, listStyle.Render(m.mainList.View()), rightPane)
 
 	var statusText string
-	if m.mode == flameGraphView {
+	if m.mode == labelsView {
+		statusText = m.styles.Status.Render("F1/? help | tab focus | enter focus label | x ignore label | g group by key | backspace clear | L exit labels | t view | q quit")
+	} else if m.mode == modulesView {
+		statusText = m.styles.Status.Render("F1/? help | tab focus | enter drill into module | backspace clear | M exit modules | t view | q quit")
+	} else if m.mode == flameGraphView {
 		navHelp := "tab focus | ←↑↓→ nav | enter zoom"
+		if m.isDiffMode {
+			if m.flameWidthBefore {
+				navHelp += " | w width (before)"
+			} else {
+				navHelp += " | w width (after)"
+			}
+		}
 		if m.flameGraphFocus != m.flameGraphRoot {
 			statusText = m.styles.Status.Render(
 				fmt.Sprintf("F1/? help | esc zoom out | %s | f exit flame | q quit", navHelp),
@@ -1033,22 +1610,37 @@ func (m model) View() string {
 			"F1/? help",
 			fmt.Sprintf("s sort (%s)", sortStr),
 			"t view",
-			"p project",
+			fmt.Sprintf("p class (%s)", m.classFilter),
 		}
 
+		helpItems = append(helpItems, "c mode", "f flame")
+		if m.isDiffMode {
+			helpItems = append(helpItems, fmt.Sprintf("%% normalize (%s)", m.diffNorm))
+		}
 		if !m.isDiffMode {
-			helpItems = append(helpItems, "c mode", "f flame")
+			helpItems = append(helpItems, "F filter", "L labels", "M modules", "a attribute", "[] {} prune", "S save")
+			if m.binary != nil {
+				helpItems = append(helpItems, "d disasm")
+			}
 		}
 
 		if m.mode == sourceView {
 			// Add hint for focus changing after help hint.
-			helpItems = slices.Insert(helpItems, 1, "tab focus", "←↑↓→ nav")
+			helpItems = slices.Insert(helpItems, 1, "tab focus", "←↑↓→ nav", "n/N hot line")
 		}
 
 		helpItems = append(helpItems, "r resize", "q quit")
 		statusText = m.styles.Status.Render(strings.Join(helpItems, " | "))
 	}
 
+	if m.showFilterPrompt {
+		// The prompt replaces the key hints while it is open.
+		statusText = m.styles.Status.Render(m.filterPrompt.View() + "  (enter apply, esc cancel)")
+	}
+	if m.showSavePrompt {
+		statusText = m.styles.Status.Render(m.savePrompt.View() + "  (enter save, esc cancel; .json for speedscope)")
+	}
+
 	var liveHelp string
 	if m.isLiveMode {
 		if m.isPaused {
@@ -1062,6 +1654,52 @@ func (m model) View() string {
 	return m.styles.Base.Render(lipgloss.JoinVertical(lipgloss.Left, header, panes, statusText))
 }
 
+// formatSignedValue formats a delta with an explicit sign but no styling, for
+// text that is rendered inside another style such as the status bar.
+func formatSignedValue(value int64, unit string) string {
+	if value < 0 {
+		return "-" + formatValue(-value, unit)
+	}
+	return "+" + formatValue(value, unit)
+}
+
+// formatSignificantImpact labels a change measured over repeated runs by whether
+// it is outside run-to-run noise, like benchstat: significant changes keep their
+// color and show a 95% confidence interval, noise is shown plainly as "~".
+func formatSignificantImpact(ratio, delta float64, unit string, s *Styles, norm diffNormalizer, stats *DiffStats) string {
+	var change string
+	switch {
+	case math.IsInf(ratio, 1):
+		change = "introduced"
+	case ratio == 0.0:
+		change = "eliminated"
+	case shouldShowRatio(ratio):
+		change = formatRatio(ratio, unit)
+	default:
+		change = formatPercentageChange(ratio)
+	}
+
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+	}
+	deltaStr := sign + norm.format(math.Abs(delta), unit)
+	if norm.mode == diffAbsolute {
+		// The interval is computed on raw per-run values, so it only applies to absolute deltas.
+		deltaStr += " ±" + norm.format((stats.CIHigh-stats.CILow)/2, unit)
+	}
+
+	if !stats.Significant() {
+		return fmt.Sprintf("%s (~ noise, p=%.2f)", deltaStr, stats.PValue)
+	}
+	if delta < 0 {
+		deltaStr = s.DiffNegative.Render(deltaStr)
+	} else {
+		deltaStr = s.DiffPositive.Render(deltaStr)
+	}
+	return fmt.Sprintf("%s (%s, significant, p=%.3f)", deltaStr, change, stats.PValue)
+}
+
 func formatDelta(value int64, unit string, s *Styles) string {
 	formattedVal := formatValue(abs(value), unit)
 	if value > 0 {
@@ -1074,16 +1712,20 @@ func formatDelta(value int64, unit string, s *Styles) string {
 }
 
 // formatDiffImpact formats diff changes with impact-focused language and smart filtering
-func formatDiffImpact(ratio float64, delta int64, unit string, s *Styles, totalValue int64) string {
+func formatDiffImpact(ratio, delta float64, unit string, s *Styles, norm diffNormalizer, stats *DiffStats) string {
+	if stats != nil {
+		return formatSignificantImpact(ratio, delta, unit, s, norm, stats)
+	}
+
 	// Calculate impact as percentage of total
 	impactPercent := 0.0
-	if totalValue != 0 {
-		impactPercent = (float64(abs(delta)) / float64(abs(totalValue))) * 100
+	if norm.impactBase != 0 {
+		impactPercent = (math.Abs(delta) / math.Abs(norm.impactBase)) * 100
 	}
 
 	// Handle special cases with clearer language
 	if math.IsInf(ratio, 1) {
-		formattedVal := formatValue(delta, unit)
+		formattedVal := norm.format(delta, unit)
 		if impactPercent >= 1.0 {
 			return s.DiffPositive.Render(fmt.Sprintf("+%s (introduced)", formattedVal))
 		} else {
@@ -1091,7 +1733,7 @@ func formatDiffImpact(ratio float64, delta int64, unit string, s *Styles, totalV
 		}
 	}
 	if ratio == 0.0 {
-		formattedVal := formatValue(-delta, unit)
+		formattedVal := norm.format(-delta, unit)
 		if impactPercent >= 1.0 {
 			return s.DiffNegative.Render(fmt.Sprintf("-%s (eliminated)", formattedVal))
 		} else {
@@ -1099,7 +1741,12 @@ func formatDiffImpact(ratio float64, delta int64, unit string, s *Styles, totalV
 		}
 	}
 
-	deltaStr := formatDelta(delta, unit, s)
+	deltaStr := norm.format(0, unit)
+	if delta > 0 {
+		deltaStr = s.DiffPositive.Render("+" + norm.format(delta, unit))
+	} else if delta < 0 {
+		deltaStr = s.DiffNegative.Render("-" + norm.format(-delta, unit))
+	}
 
 	// Show impact level for significant changes
 	if impactPercent >= 5.0 {
@@ -1219,6 +1866,20 @@ func min(a, b int64) int64 {
 	return b
 }
 
+// revisionNote names the git revision source is shown at, and warns when the
+// selected function's file has changed since then.
+func (m model) revisionNote() string {
+	if m.sources == nil || m.sources.Revision == "" {
+		return ""
+	}
+	note := m.styles.ProjectCode.Render("Source at revision: " + m.sources.ShortRevision())
+	if selected, ok := m.mainList.SelectedItem().(listItem); ok && m.sources.Drifted(selected.node.FileName, selected.node.Name) {
+		warning := fmt.Sprintf("⚠ %s has changed since; the source pane shows it as built", shortFileName(selected.node.FileName))
+		note = lipgloss.JoinHorizontal(lipgloss.Left, note, " ", m.styles.DiffNegative.Render(warning))
+	}
+	return note
+}
+
 func (m model) renderDiagnosticHeader() string {
 	var topContent string
 
@@ -1239,6 +1900,48 @@ func (m model) renderDiagnosticHeader() string {
 		)
 	} else {
 		topContent = m.styles.Status.Render(m.sourceInfo)
+		if m.lastError != nil {
+			topContent = lipgloss.JoinHorizontal(lipgloss.Left,
+				topContent,
+				" ",
+				m.styles.DiffNegative.Render(fmt.Sprintf("ERROR: %v", m.lastError)),
+			)
+		}
+	}
+
+	if !m.filters.IsEmpty() {
+		topContent = lipgloss.JoinVertical(lipgloss.Left,
+			topContent,
+			m.styles.ProjectCode.Render("Filters: "+m.filters.String()),
+		)
+	}
+	if m.pivotKey != "" {
+		topContent = lipgloss.JoinVertical(lipgloss.Left,
+			topContent,
+			m.styles.ProjectCode.Render("Grouped by label: "+m.pivotKey),
+		)
+	}
+	if m.prune.IsActive() {
+		topContent = lipgloss.JoinVertical(lipgloss.Left,
+			topContent,
+			m.styles.ProjectCode.Render("Pruned: "+m.prune.String()),
+		)
+	}
+	if m.attributed {
+		topContent = lipgloss.JoinVertical(lipgloss.Left,
+			topContent,
+			m.styles.ProjectCode.Render("Project-attributed: library and runtime cost is charged to the nearest project frame"),
+		)
+	}
+	revisionNote := m.revisionNote()
+	if revisionNote != "" {
+		topContent = lipgloss.JoinVertical(lipgloss.Left, topContent, revisionNote)
+	}
+	if m.savedPath != "" {
+		topContent = lipgloss.JoinVertical(lipgloss.Left,
+			topContent,
+			m.styles.DiffPositive.Render("Saved: "+m.savedPath+m.savedNote()),
+		)
 	}
 
 	if m.profileData == nil || len(m.profileData.Views) == 0 {
@@ -1250,6 +1953,12 @@ func (m model) renderDiagnosticHeader() string {
 
 	if strings.HasPrefix(currentView.Name, "Diff:") {
 		diagnosticText = "💡 Comparing two profiles. Green (+) means more time/memory was used in the second profile. Red (-) means less."
+		switch newDiffNormalizer(m.diffNorm, currentView, m.profileData).mode {
+		case diffShare:
+			diagnosticText += "\n  Normalized: each function's share of its profile's total, in percentage points."
+		case diffRate:
+			diagnosticText += "\n  Normalized: each profile's values per second of profiling."
+		}
 	} else if strings.Contains(currentView.Name, "cpu") || strings.Contains(currentView.Name, "samples") {
 		var cpuTimeView *ProfileView
 		for _, v := range m.profileData.Views {
@@ -1270,7 +1979,7 @@ func (m model) renderDiagnosticHeader() string {
 			summary := fmt.Sprintf("Profiled for %s. Your program was busy for %s (%.1f%%).", profDurStr, sampDurStr, busyPercent)
 			var hint string
 			if busyPercent < 5.0 {
-				hint = "This program is likely I/O-bound or blocked. The CPU profile may not show the real bottleneck."
+				hint = "This program is likely I/O-bound or blocked. The CPU profile may not show the real bottleneck; open a runtime/trace file to see where it waits."
 			} else if busyPercent > 95.0 {
 				hint = "This program is CPU-bound. The functions below are the main contributors to CPU usage."
 			} else {
@@ -1284,7 +1993,7 @@ func (m model) renderDiagnosticHeader() string {
 		diagnosticText = "💡 Think 'Total Water Poured'. This shows all memory allocated over time. Use this to find code causing GC pressure."
 	}
 
-	if diagnosticText == "" {
+	if diagnosticText == "" && m.filters.IsEmpty() && m.pivotKey == "" && !m.prune.IsActive() && m.lastError == nil && revisionNote == "" && !m.attributed && m.savedPath == "" {
 		// If there's no special hint, just show the source info plainly without a clunky box.
 		return m.styles.Status.Render(m.sourceInfo)
 	}
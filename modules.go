@@ -0,0 +1,214 @@
+// modules.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// Modules of code that does not belong to a versioned module.
+const (
+	stdModule     = "std"
+	runtimeModule = "runtime"
+	unknownModule = "(not Go)"
+)
+
+// ModuleCost is the cost of all functions of one Go module.
+type ModuleCost struct {
+	Module    string
+	Version   string // Empty when neither the file paths nor go.mod tell.
+	Class     CodeClass
+	Flat      int64
+	Cum       int64 // Each sample counts once, however many of the module's frames it has.
+	Functions int
+}
+
+func (c ModuleCost) String() string {
+	if c.Version == "" {
+		return c.Module
+	}
+	return c.Module + "@" + c.Version
+}
+
+// funcModule returns the module a function belongs to. Project code belongs to
+// the longest project module matching its package or file, and dependencies to the module@version
+// in their file path, as recorded from the module cache, or else to the
+// longest module required by go.mod that contains their package.
+func funcModule(node *FuncNode, modulePaths []string, requires map[string]string) (module, version string) {
+	pkg := funcPackage(node.Name)
+	switch node.Class {
+	case classStdlib:
+		return stdModule, ""
+	case classRuntime:
+		return runtimeModule, ""
+	case classProject:
+		for _, modulePath := range modulePaths {
+			modulePath = strings.TrimSuffix(modulePath, "/")
+			if (pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")) && len(modulePath) > len(module) {
+				module = modulePath
+			}
+		}
+		for _, modulePath := range modulePaths {
+			if module == "" && strings.Contains(node.FileName, strings.TrimSuffix(modulePath, "/")+"/") {
+				module = strings.TrimSuffix(modulePath, "/")
+			}
+		}
+		// Package main is named after no module; with one module it is that one's.
+		if module == "" && len(modulePaths) == 1 {
+			module = strings.TrimSuffix(modulePaths[0], "/")
+		}
+		if module == "" {
+			module = pkg
+		}
+		return module, ""
+	}
+
+	if module, version, ok := moduleFromPath(node.FileName); ok {
+		return module, version
+	}
+	if pkg == "" {
+		return unknownModule, ""
+	}
+	for required := range requires {
+		if (pkg == required || strings.HasPrefix(pkg, required+"/")) && len(required) > len(module) {
+			module = required
+		}
+	}
+	if module != "" {
+		return module, requires[module]
+	}
+	return pkg, ""
+}
+
+// moduleFromPath extracts the module path and version from a file in the
+// module cache, like /go/pkg/mod/github.com/!burnt!sushi/toml@v1.3.2/decode.go,
+// or the same path relative to the cache as recorded with -trimpath.
+func moduleFromPath(file string) (module, version string, ok bool) {
+	slash := filepath.ToSlash(file)
+	if _, rest, found := strings.Cut(slash, "/pkg/mod/"); found {
+		slash = rest
+	} else if strings.HasPrefix(slash, "/") {
+		return "", "", false
+	}
+	parts := strings.Split(slash, "/")
+	for i, part := range parts {
+		name, version, found := strings.Cut(part, "@")
+		if !found {
+			continue
+		}
+		module := strings.Join(append(parts[:i:i], name), "/")
+		return unescapeModulePath(module), version, true
+	}
+	return "", "", false
+}
+
+// unescapeModulePath undoes escapeModulePath.
+func unescapeModulePath(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CollectModuleCosts sums the cost of a view by module, most expensive first.
+// Flat costs come from the view's functions; cumulative costs are taken from
+// the samples of p so that calls within a module are not counted twice.
+func CollectModuleCosts(p *profile.Profile, sampleIndex int, view *ProfileView, modulePaths []string, requires map[string]string) []ModuleCost {
+	costs := make(map[string]*ModuleCost)
+	moduleOf := make(map[uint64]*ModuleCost)
+	for id, node := range view.Nodes {
+		module, version := funcModule(node, modulePaths, requires)
+		key := module + "@" + version
+		cost, ok := costs[key]
+		if !ok {
+			cost = &ModuleCost{Module: module, Version: version, Class: node.Class}
+			costs[key] = cost
+		}
+		cost.Flat += node.FlatValue
+		cost.Functions++
+		moduleOf[id] = cost
+	}
+
+	if p != nil && sampleIndex < len(p.SampleType) {
+		for _, s := range p.Sample {
+			val := s.Value[sampleIndex]
+			if val == 0 {
+				continue
+			}
+			seen := make(map[*ModuleCost]struct{})
+			for _, loc := range s.Location {
+				for _, line := range loc.Line {
+					if line.Function == nil {
+						continue
+					}
+					cost, ok := moduleOf[line.Function.ID]
+					if !ok {
+						continue
+					}
+					if _, dup := seen[cost]; !dup {
+						seen[cost] = struct{}{}
+						cost.Cum += val
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]ModuleCost, 0, len(costs))
+	for _, cost := range costs {
+		result = append(result, *cost)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cum != result[j].Cum {
+			return result[i].Cum > result[j].Cum
+		}
+		return result[i].String() < result[j].String()
+	})
+	return result
+}
+
+// moduleItem is a module in the modules panel.
+type moduleItem struct {
+	cost       ModuleCost
+	unit       string
+	totalValue int64
+	drilled    bool // The function list only shows this module's functions.
+	styles     *Styles
+}
+
+func (i moduleItem) Title() string {
+	title := i.styles.classStyle(i.cost.Class).Render(i.cost.String())
+	if i.drilled {
+		return i.styles.DiffPositive.Render("▶ ") + title
+	}
+	return title
+}
+
+func (i moduleItem) Description() string {
+	percent := func(v int64) float64 {
+		if i.totalValue == 0 {
+			return 0
+		}
+		return float64(v) / float64(i.totalValue) * 100
+	}
+	return fmt.Sprintf("flat %s (%.1f%%) · cum %s (%.1f%%) · %d funcs · %s",
+		formatValue(i.cost.Flat, i.unit), percent(i.cost.Flat),
+		formatValue(i.cost.Cum, i.unit), percent(i.cost.Cum),
+		i.cost.Functions, i.cost.Class)
+}
+
+func (i moduleItem) FilterValue() string { return i.cost.String() }
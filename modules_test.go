@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestCollectModuleCosts(t *testing.T) {
+	funcs := []*profile.Function{
+		{ID: 1, Name: "main.main", Filename: "/src/svc/main.go"},
+		{ID: 2, Name: "github.com/org/svc/store.Load", Filename: "/src/svc/store/load.go"},
+		{ID: 3, Name: "github.com/lib/pq.(*conn).query", Filename: "/root/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go"},
+		{ID: 4, Name: "github.com/lib/pq.(*conn).recv", Filename: "/root/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go"},
+		{ID: 5, Name: "gopkg.in/yaml%2ev3.Unmarshal", Filename: "/build/vendor/gopkg.in/yaml.v3/yaml.go"},
+		{ID: 6, Name: "encoding/json.Unmarshal", Filename: "/usr/local/go/src/encoding/json/decode.go"},
+		{ID: 7, Name: "runtime.mallocgc", Filename: "/usr/local/go/src/runtime/malloc.go"},
+	}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Function:   funcs,
+	}
+	for _, fn := range funcs {
+		p.Location = append(p.Location, &profile.Location{ID: fn.ID, Line: []profile.Line{{Function: fn, Line: 1}}})
+	}
+	stack := func(value int64, ids ...int) {
+		s := &profile.Sample{Value: []int64{value}}
+		for _, id := range ids {
+			s.Location = append(s.Location, p.Location[id-1])
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	stack(50, 7, 4, 3, 2, 1) // mallocgc <- recv <- query <- Load <- main
+	stack(30, 4, 3, 2, 1)
+	stack(15, 6, 5, 1)
+	stack(5, 5, 1)
+
+	data, err := NewProfileData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modulePaths := []string{"github.com/org/svc"}
+	classifyCode(data, modulePaths)
+	costs := CollectModuleCosts(p, 0, data.Views[0], modulePaths, map[string]string{"gopkg.in/yaml.v3": "v3.0.1"})
+
+	got := make(map[string]ModuleCost)
+	for _, c := range costs {
+		got[c.String()] = c
+	}
+	for _, want := range []ModuleCost{
+		{Module: "github.com/org/svc", Class: classProject, Flat: 0, Cum: 100, Functions: 2},
+		{Module: "github.com/lib/pq", Version: "v1.10.9", Class: classThirdParty, Flat: 30, Cum: 80, Functions: 2},
+		{Module: "gopkg.in/yaml.v3", Version: "v3.0.1", Class: classThirdParty, Flat: 5, Cum: 20, Functions: 1},
+		{Module: stdModule, Class: classStdlib, Flat: 15, Cum: 15, Functions: 1},
+		{Module: runtimeModule, Class: classRuntime, Flat: 50, Cum: 50, Functions: 1},
+	} {
+		if got[want.String()] != want {
+			t.Errorf("%s = %+v, want %+v", want, got[want.String()], want)
+		}
+	}
+	if costs[0].Module != "github.com/org/svc" || len(costs) != 5 {
+		t.Errorf("modules should be sorted by cum, got %v", costs)
+	}
+}
+
+func TestModuleFromPath(t *testing.T) {
+	for _, tc := range []struct {
+		file, module, version string
+	}{
+		{"/home/ci/go/pkg/mod/github.com/!burnt!sushi/toml@v1.3.2/decode.go", "github.com/BurntSushi/toml", "v1.3.2"},
+		{"golang.org/x/sync@v0.7.0/errgroup/errgroup.go", "golang.org/x/sync", "v0.7.0"},
+		{"/build/src/github.com/org/svc/main.go", "", ""},
+	} {
+		module, version, _ := moduleFromPath(tc.file)
+		if module != tc.module || version != tc.version {
+			t.Errorf("moduleFromPath(%s) = %s@%s, want %s@%s", tc.file, module, version, tc.module, tc.version)
+		}
+	}
+}
@@ -18,6 +18,14 @@ type FlameNode struct {
 	Value    int64
 	Children []*FlameNode
 	Parent   *FlameNode // Parent pointer for easier traversal (zoom, breadcrumbs)
+
+	Synthetic bool // Not a real function, e.g. a label bucket when pivoting.
+
+	// Differential flame graphs keep both values; Value holds whichever one
+	// is currently used for the frame widths.
+	Diff        bool
+	BeforeValue int64
+	AfterValue  int64
 }
 
 // FunctionProfile holds the raw data for a function.
@@ -52,18 +60,64 @@ type FuncNode struct {
 	CumRatio   float64
 	ChangeType ChangeType
 
-	IsProjectCode bool
+	// Raw values from both profiles of a diff, so the delta can be normalized.
+	FlatBefore, FlatAfter int64
+	CumBefore, CumAfter   int64
+
+	// Significance of the change when diffing two sets of repeated runs.
+	FlatStats, CumStats *DiffStats
+
+	// Values over an ordered series of profiles, oldest first, and the
+	// average change of the cumulative value per profile.
+	Series []LineCost
+	Slope  float64
+
+	Class         CodeClass
+	IsProjectCode bool // Class is classProject.
+
+	// Per-line costs within this function, keyed by source line number.
+	Lines map[int]*LineCost
+
+	// Cumulative value split by the values of the pivot label, if one is selected.
+	LabelBreakdown map[string]int64
 
 	// Graph structure
 	In  map[*FuncNode]int64 // Callers: map[caller]edge_weight
 	Out map[*FuncNode]int64 // Callees: map[callee]edge_weight
 }
 
+// LineCost holds the flat and cumulative values attributed to one source line.
+type LineCost struct {
+	Flat int64
+	Cum  int64
+}
+
+// HotLines returns the line numbers of this function that carry any cost,
+// ordered from hottest to coldest by cumulative value.
+func (n *FuncNode) HotLines() []int {
+	lines := make([]int, 0, len(n.Lines))
+	for line := range n.Lines {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		ci, cj := n.Lines[lines[i]], n.Lines[lines[j]]
+		if ci.Cum != cj.Cum {
+			return ci.Cum > cj.Cum
+		}
+		return lines[i] < lines[j]
+	})
+	return lines
+}
+
 type ProfileView struct {
 	Name       string
+	SampleType string // The pprof sample type this view was built from, e.g. "alloc_space".
 	Unit       string
 	TotalValue int64                // The sum of all samples in this view.
 	Nodes      map[uint64]*FuncNode // All nodes in this view, indexed by function ID
+
+	// In diff mode, the totals of the two profiles that were compared.
+	BeforeTotal, AfterTotal int64
 }
 
 // ProfileData holds all the parsed views from a single pprof file.
@@ -71,14 +125,29 @@ type ProfileData struct {
 	DurationNanos int64
 	Views         []*ProfileView
 	RawPprof      *profile.Profile
+	BasePprof     *profile.Profile // The "before" profile in diff mode; RawPprof is the "after" one.
+	MergedCount   int              // Number of profiles merged into this one, 0 for a single profile.
+
+	BaseDurationNanos int64 // Duration of the "before" profile in diff mode.
+	SeriesLength      int   // Number of profiles in a time series, 0 outside series mode.
+}
+
+// lineKey identifies a single source line of a function.
+type lineKey struct {
+	funcID uint64
+	line   int
 }
 
-func ParsePprofFile(reader io.Reader) (*ProfileData, error) {
-	p, err := profile.Parse(reader)
+func ParsePprofFile(reader io.Reader, symbolizer *Symbolizer) (*ProfileData, error) {
+	p, err := parseProfile(reader, symbolizer)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse pprof data: %w", err)
 	}
+	return NewProfileData(p)
+}
 
+// NewProfileData builds one ProfileView per sample type of an already parsed profile.
+func NewProfileData(p *profile.Profile) (*ProfileData, error) {
 	profileData := &ProfileData{
 		RawPprof:      p,
 		DurationNanos: p.DurationNanos,
@@ -86,9 +155,10 @@ func ParsePprofFile(reader io.Reader) (*ProfileData, error) {
 
 	for i, sampleType := range p.SampleType {
 		view := &ProfileView{
-			Name:  fmt.Sprintf("%s (%s)", sampleType.Type, sampleType.Unit),
-			Unit:  sampleType.Unit,
-			Nodes: make(map[uint64]*FuncNode),
+			Name:       fmt.Sprintf("%s (%s)", sampleType.Type, sampleType.Unit),
+			SampleType: sampleType.Type,
+			Unit:       sampleType.Unit,
+			Nodes:      make(map[uint64]*FuncNode),
 		}
 
 		var totalValueForView int64
@@ -102,8 +172,12 @@ func ParsePprofFile(reader io.Reader) (*ProfileData, error) {
 			}
 			totalValueForView += val
 
+			// A line that appears several times in one stack (recursion) only
+			// counts once towards its cumulative value, like pprof -list.
+			seenLines := make(map[lineKey]struct{})
+
 			for j, loc := range s.Location {
-				for _, line := range loc.Line {
+				for k, line := range loc.Line {
 					fun := line.Function
 					if _, ok := view.Nodes[fun.ID]; !ok {
 						view.Nodes[fun.ID] = &FuncNode{
@@ -111,12 +185,28 @@ func ParsePprofFile(reader io.Reader) (*ProfileData, error) {
 							Name:      fun.Name,
 							FileName:  fun.Filename,
 							StartLine: int(line.Line),
+							Lines:     make(map[int]*LineCost),
 							In:        make(map[*FuncNode]int64),
 							Out:       make(map[*FuncNode]int64),
 						}
 					}
 					node := view.Nodes[fun.ID]
 					node.CumValue += val
+
+					lineNum := int(line.Line)
+					cost, ok := node.Lines[lineNum]
+					if !ok {
+						cost = &LineCost{}
+						node.Lines[lineNum] = cost
+					}
+					if j == 0 && k == 0 {
+						cost.Flat += val
+					}
+					key := lineKey{funcID: fun.ID, line: lineNum}
+					if _, seen := seenLines[key]; !seen {
+						seenLines[key] = struct{}{}
+						cost.Cum += val
+					}
 				}
 				if j == 0 && len(loc.Line) > 0 {
 					fun := loc.Line[0].Function
@@ -170,6 +260,32 @@ func ParsePprofFile(reader io.Reader) (*ProfileData, error) {
 	return profileData, nil
 }
 
+// MergePprofFiles parses several profiles of the same type, e.g. CPU profiles from
+// different replicas or benchmark runs, and merges them into one aggregate profile.
+func MergePprofFiles(readers []io.Reader, symbolizer *Symbolizer) (*ProfileData, error) {
+	if len(readers) == 0 {
+		return nil, fmt.Errorf("no profiles to merge")
+	}
+	profiles := make([]*profile.Profile, 0, len(readers))
+	for i, reader := range readers {
+		p, err := parseProfile(reader, symbolizer)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse profile %d: %w", i+1, err)
+		}
+		profiles = append(profiles, p)
+	}
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not merge profiles: %w", err)
+	}
+	data, err := NewProfileData(merged)
+	if err != nil {
+		return nil, err
+	}
+	data.MergedCount = len(profiles)
+	return data, nil
+}
+
 // formatValue intelligently formats a value based on its unit.
 func formatValue(value int64, unit string) string {
 	switch unit {
@@ -214,16 +330,20 @@ func hashString(s string) uint64 {
 	return h.Sum64()
 }
 
-func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
-	beforeData, err := ParsePprofFile(beforeReader)
+func DiffPprofFiles(beforeReader, afterReader io.Reader, symbolizer *Symbolizer) (*ProfileData, error) {
+	beforeData, err := ParsePprofFile(beforeReader, symbolizer)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse 'before' profile: %w", err)
 	}
-	afterData, err := ParsePprofFile(afterReader)
+	afterData, err := ParsePprofFile(afterReader, symbolizer)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse 'after' profile: %w", err)
 	}
+	return diffProfileData(beforeData, afterData)
+}
 
+// diffProfileData computes the per-function deltas between two parsed profiles.
+func diffProfileData(beforeData, afterData *ProfileData) (*ProfileData, error) {
 	beforeViewsMap := make(map[string]*ProfileView)
 	for _, v := range beforeData.Views {
 		baseName := strings.Split(v.Name, " ")[0]
@@ -231,8 +351,10 @@ func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
 	}
 
 	diffProfileData := &ProfileData{
-		DurationNanos: afterData.DurationNanos,
-		RawPprof:      afterData.RawPprof,
+		DurationNanos:     afterData.DurationNanos,
+		RawPprof:          afterData.RawPprof,
+		BasePprof:         beforeData.RawPprof,
+		BaseDurationNanos: beforeData.DurationNanos,
 	}
 
 	for _, afterView := range afterData.Views {
@@ -243,24 +365,25 @@ func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
 		}
 
 		diffView := &ProfileView{
-			Name:       fmt.Sprintf("Diff: %s", strings.TrimPrefix(afterView.Name, "Diff: ")),
-			Unit:       afterView.Unit,
-			Nodes:      make(map[uint64]*FuncNode),
-			TotalValue: afterView.TotalValue - beforeView.TotalValue,
+			Name:        fmt.Sprintf("Diff: %s", strings.TrimPrefix(afterView.Name, "Diff: ")),
+			SampleType:  afterView.SampleType,
+			Unit:        afterView.Unit,
+			Nodes:       make(map[uint64]*FuncNode),
+			TotalValue:  afterView.TotalValue - beforeView.TotalValue,
+			BeforeTotal: beforeView.TotalValue,
+			AfterTotal:  afterView.TotalValue,
 		}
 
 		// Create function signature to node mapping for stable matching
 		// Function signature: "name|filename" (excluding startline to avoid duplicates from inlining)
 		beforeFuncMap := make(map[string]*FuncNode)
 		for _, node := range beforeView.Nodes {
-			sig := fmt.Sprintf("%s|%s", node.Name, node.FileName)
-			beforeFuncMap[sig] = node
+			beforeFuncMap[funcSignature(node)] = node
 		}
 
 		afterFuncMap := make(map[string]*FuncNode)
 		for _, node := range afterView.Nodes {
-			sig := fmt.Sprintf("%s|%s", node.Name, node.FileName)
-			afterFuncMap[sig] = node
+			afterFuncMap[funcSignature(node)] = node
 		}
 
 		// Get all unique function signatures
@@ -311,6 +434,9 @@ func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
 				diffNode.CumDelta += afterNode.CumValue
 			}
 
+			diffNode.FlatBefore, diffNode.FlatAfter = beforeFlat, afterFlat
+			diffNode.CumBefore, diffNode.CumAfter = beforeCum, afterCum
+
 			// Calculate ratios
 			diffNode.FlatRatio = calculateRatio(beforeFlat, afterFlat)
 			diffNode.CumRatio = calculateRatio(beforeCum, afterCum)
@@ -326,7 +452,8 @@ func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
 
 			diffView.Nodes[stableID] = diffNode
 		}
-		// TODO: Fix edge processing to work with signature-based matching
+
+		diffEdges(diffView, beforeView, afterView)
 
 		diffProfileData.Views = append(diffProfileData.Views, diffView)
 	}
@@ -338,9 +465,49 @@ func DiffPprofFiles(beforeReader, afterReader io.Reader) (*ProfileData, error) {
 	return diffProfileData, nil
 }
 
+// funcSignature identifies a function across profiles: "name|filename"
+// (excluding startline to avoid duplicates from inlining).
+func funcSignature(node *FuncNode) string {
+	return fmt.Sprintf("%s|%s", node.Name, node.FileName)
+}
+
+// edgeSignature identifies a caller -> callee edge across profiles.
+type edgeSignature struct {
+	caller, callee string
+}
+
+// diffEdges fills the In/Out maps of the diff view's nodes with the change in
+// weight of every call edge, matching edges by the signatures of both ends.
+// Edges that exist in only one of the profiles are included as pure additions
+// or removals.
+func diffEdges(diffView, beforeView, afterView *ProfileView) {
+	deltas := make(map[edgeSignature]int64)
+	for _, node := range beforeView.Nodes {
+		for callee, weight := range node.Out {
+			deltas[edgeSignature{funcSignature(node), funcSignature(callee)}] -= weight
+		}
+	}
+	for _, node := range afterView.Nodes {
+		for callee, weight := range node.Out {
+			deltas[edgeSignature{funcSignature(node), funcSignature(callee)}] += weight
+		}
+	}
+
+	for edge, delta := range deltas {
+		caller, okCaller := diffView.Nodes[hashString(edge.caller)]
+		callee, okCallee := diffView.Nodes[hashString(edge.callee)]
+		if !okCaller || !okCallee {
+			continue
+		}
+		caller.Out[callee] = delta
+		callee.In[caller] = delta
+	}
+}
+
 // BuildFlameGraph constructs a full, cumulative flame graph tree, correctly
-// handling inlined function calls.
-func BuildFlameGraph(p *profile.Profile, sampleIndex int, unit string) *FlameNode {
+// handling inlined function calls. When pivotKey is set, every value of that
+// label becomes a synthetic frame directly below the root.
+func BuildFlameGraph(p *profile.Profile, sampleIndex int, unit string, pivotKey string) *FlameNode {
 	root := &FlameNode{Name: "root"}
 	if p == nil || len(p.Sample) == 0 || sampleIndex >= len(p.SampleType) {
 		return root
@@ -355,47 +522,115 @@ func BuildFlameGraph(p *profile.Profile, sampleIndex int, unit string) *FlameNod
 		}
 		totalValue += val
 
-		// Start with the root of our flame graph tree for this sample.
-		currentNode := root
-
-		// Iterate through the locations in the stack, from caller to callee.
-		for i := len(s.Location) - 1; i >= 0; i-- {
-			loc := s.Location[i]
-
-			// Unroll the inlined functions within this location.
-			// The proto spec says the last line is the caller and previous lines
-			// were inlined into it. So we iterate backward through the lines too.
-			for j := len(loc.Line) - 1; j >= 0; j-- {
-				line := loc.Line[j]
-				funcName := line.Function.Name
-
-				var childNode *FlameNode
-				for _, child := range currentNode.Children {
-					if child.Name == funcName {
-						childNode = child
-						break
-					}
-				}
+		// The value applies to every frame of the stack.
+		for _, node := range addFlameStack(root, s, pivotKey) {
+			node.Value += val
+		}
+	}
 
-				if childNode == nil {
-					childNode = &FlameNode{Name: funcName, Parent: currentNode}
-					currentNode.Children = append(currentNode.Children, childNode)
-				}
+	root.Value = totalValue
+
+	sortChildren(root)
+	return root
+}
+
+// addFlameStack makes sure every frame of the sample's stack exists in the tree
+// below root and returns them, from the outermost caller to the leaf.
+func addFlameStack(root *FlameNode, s *profile.Sample, pivotKey string) []*FlameNode {
+	var path []*FlameNode
+
+	// Start with the root of our flame graph tree for this sample.
+	currentNode := root
+	if pivotKey != "" {
+		currentNode = findOrAddChild(root, pivotKey+"="+samplePivotValue(s, pivotKey))
+		currentNode.Synthetic = true
+		path = append(path, currentNode)
+	}
 
-				// The value applies to this function and all its callers.
-				childNode.Value += val
+	// Iterate through the locations in the stack, from caller to callee.
+	for i := len(s.Location) - 1; i >= 0; i-- {
+		loc := s.Location[i]
+
+		// Unroll the inlined functions within this location.
+		// The proto spec says the last line is the caller and previous lines
+		// were inlined into it. So we iterate backward through the lines too.
+		for j := len(loc.Line) - 1; j >= 0; j-- {
+			line := loc.Line[j]
+			childNode := findOrAddChild(currentNode, line.Function.Name)
+			path = append(path, childNode)
+
+			// Descend into this frame. The next frame (either from the next
+			// inlined function or the next location) will be its child.
+			currentNode = childNode
+		}
+	}
+	return path
+}
 
-				// Descend into this frame. The next frame (either from the next
-				// inlined function or the next location) will be its child.
-				currentNode = childNode
+// BuildDiffFlameGraph builds one flame graph tree from both profiles of a diff,
+// recording each frame's before and after values. Frame widths follow the
+// after profile unless widthFromBefore is set.
+func BuildDiffFlameGraph(before, after *profile.Profile, sampleType string, widthFromBefore bool) *FlameNode {
+	root := &FlameNode{Name: "root", Diff: true}
+	add := func(p *profile.Profile, isBefore bool) {
+		if p == nil {
+			return
+		}
+		sampleIndex, err := p.SampleIndexByName(sampleType)
+		if err != nil {
+			return
+		}
+		for _, s := range p.Sample {
+			val := s.Value[sampleIndex]
+			if val == 0 {
+				continue
+			}
+			nodes := append([]*FlameNode{root}, addFlameStack(root, s, "")...)
+			for _, node := range nodes {
+				node.Diff = true
+				if isBefore {
+					node.BeforeValue += val
+				} else {
+					node.AfterValue += val
+				}
 			}
 		}
 	}
+	add(before, true)
+	add(after, false)
 
-	root.Value = totalValue
+	setFlameWidthBasis(root, widthFromBefore)
+	return root
+}
 
+// setFlameWidthBasis switches the frame widths of a differential flame graph
+// between the before and after values, and re-sorts the frames accordingly.
+func setFlameWidthBasis(root *FlameNode, fromBefore bool) {
+	var visit func(n *FlameNode)
+	visit = func(n *FlameNode) {
+		if fromBefore {
+			n.Value = n.BeforeValue
+		} else {
+			n.Value = n.AfterValue
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(root)
 	sortChildren(root)
-	return root
+}
+
+// findOrAddChild returns the child of parent with the given name, creating it if needed.
+func findOrAddChild(parent *FlameNode, name string) *FlameNode {
+	for _, child := range parent.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &FlameNode{Name: name, Parent: parent}
+	parent.Children = append(parent.Children, child)
+	return child
 }
 
 // sortChildren recursively sorts children of a node by value (desc) for a stable layout.
@@ -410,23 +645,3 @@ func sortChildren(node *FlameNode) {
 		sortChildren(child)
 	}
 }
-
-// AnnotateProjectCode marks nodes that belong to the user's project module.
-func annotateProjectCode(data *ProfileData, modulePath string) {
-	if data == nil || modulePath == "" {
-		return
-	}
-
-	normalizedPath := modulePath
-	if !strings.HasSuffix(normalizedPath, "/") {
-		normalizedPath += "/"
-	}
-
-	for _, view := range data.Views {
-		for _, node := range view.Nodes {
-			if strings.Contains(node.FileName, normalizedPath) {
-				node.IsProjectCode = true
-			}
-		}
-	}
-}
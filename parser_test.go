@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// newTestProfile builds a small CPU profile where main.work calls main.helper
+// from two different lines.
+func newTestProfile(t *testing.T) *profile.Profile {
+	t.Helper()
+	mainFn := &profile.Function{ID: 1, Name: "main.main", Filename: "main.go"}
+	workFn := &profile.Function{ID: 2, Name: "main.work", Filename: "main.go"}
+	helperFn := &profile.Function{ID: 3, Name: "main.helper", Filename: "helper.go"}
+
+	locMain := &profile.Location{ID: 1, Line: []profile.Line{{Function: mainFn, Line: 5}}}
+	locWork10 := &profile.Location{ID: 2, Line: []profile.Line{{Function: workFn, Line: 10}}}
+	locWork12 := &profile.Location{ID: 3, Line: []profile.Line{{Function: workFn, Line: 12}}}
+	locHelper := &profile.Location{ID: 4, Line: []profile.Line{{Function: helperFn, Line: 3}}}
+
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		Function:   []*profile.Function{mainFn, workFn, helperFn},
+		Location:   []*profile.Location{locMain, locWork10, locWork12, locHelper},
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{locHelper, locWork10, locMain}, Value: []int64{30}},
+			{Location: []*profile.Location{locHelper, locWork12, locMain}, Value: []int64{20}},
+			{Location: []*profile.Location{locWork12, locMain}, Value: []int64{5}},
+		},
+	}
+}
+
+func parseTestProfile(t *testing.T, p *profile.Profile) *ProfileData {
+	t.Helper()
+	data, err := ParsePprofFile(writeTestProfile(t, p), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	return data
+}
+
+func findNode(view *ProfileView, name string) *FuncNode {
+	for _, node := range view.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+func TestParseLineCosts(t *testing.T) {
+	data := parseTestProfile(t, newTestProfile(t))
+	work := findNode(data.Views[0], "main.work")
+	if work == nil {
+		t.Fatal("main.work not found")
+	}
+
+	tests := []struct {
+		line      int
+		flat, cum int64
+	}{
+		{line: 10, flat: 0, cum: 30},
+		{line: 12, flat: 5, cum: 25},
+	}
+	for _, tt := range tests {
+		cost, ok := work.Lines[tt.line]
+		if !ok {
+			t.Fatalf("no cost recorded for line %d", tt.line)
+		}
+		if cost.Flat != tt.flat || cost.Cum != tt.cum {
+			t.Errorf("line %d: got flat=%d cum=%d, want flat=%d cum=%d", tt.line, cost.Flat, cost.Cum, tt.flat, tt.cum)
+		}
+	}
+
+	hot := work.HotLines()
+	if len(hot) != 2 || hot[0] != 10 || hot[1] != 12 {
+		t.Errorf("HotLines() = %v, want [10 12]", hot)
+	}
+}
+
+func TestPruneProfileData(t *testing.T) {
+	t.Run("nodes are folded", func(t *testing.T) {
+		data := parseTestProfile(t, newTestProfile(t))
+		pruneProfileData(data, PruneOptions{NodeFraction: 0.95})
+		view := data.Views[0]
+
+		if findNode(view, "main.helper") != nil {
+			t.Fatal("main.helper should have been pruned")
+		}
+		folded := findNode(view, "(pruned 1 function)")
+		if folded == nil {
+			t.Fatal("pruned entry missing")
+		}
+		var flatSum int64
+		for _, node := range view.Nodes {
+			flatSum += node.FlatValue
+		}
+		if flatSum != view.TotalValue {
+			t.Errorf("flat values add up to %d, want total %d", flatSum, view.TotalValue)
+		}
+		work := findNode(view, "main.work")
+		if work.Out[folded] != 50 || folded.In[work] != 50 {
+			t.Errorf("edge to pruned entry = %d/%d, want 50", work.Out[folded], folded.In[work])
+		}
+	})
+
+	t.Run("edges are dropped", func(t *testing.T) {
+		data := parseTestProfile(t, newTestProfile(t))
+		pruneProfileData(data, PruneOptions{EdgeFraction: 0.95})
+		view := data.Views[0]
+		work, helper, main := findNode(view, "main.work"), findNode(view, "main.helper"), findNode(view, "main.main")
+		if len(work.Out) != 0 || len(helper.In) != 0 {
+			t.Errorf("small edge main.work -> main.helper should be pruned")
+		}
+		if main.Out[work] != 55 {
+			t.Errorf("large edge main.main -> main.work = %d, want 55", main.Out[work])
+		}
+	})
+}
+
+func TestPruneProfile(t *testing.T) {
+	p := newTestProfile(t)
+	data := parseTestProfile(t, p)
+	pruneProfileData(data, PruneOptions{NodeFraction: 0.95})
+	pruned := pruneProfile(data.RawPprof, 0, data.Views[0])
+	if pruned == data.RawPprof {
+		t.Fatal("pruneProfile should return a copy")
+	}
+
+	// Saving and loading the pruned profile gives back the pruned view.
+	path := filepath.Join(t.TempDir(), "pruned.pb.gz")
+	if err := exportProfile(path, pruned); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved, err := ParsePprofFile(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := saved.Views[0]
+	if findNode(view, "main.helper") != nil {
+		t.Error("main.helper should have been folded")
+	}
+	folded := findNode(view, "(pruned 1 function)")
+	if folded == nil || folded.FlatValue != 50 {
+		t.Fatalf("pruned entry = %+v, want flat 50", folded)
+	}
+	if work := findNode(view, "main.work"); work == nil || work.Out[folded] != 50 {
+		t.Errorf("main.work should call the pruned entry with 50")
+	}
+	if view.TotalValue != 55 {
+		t.Errorf("total = %d, want 55", view.TotalValue)
+	}
+	if findNode(data.Views[0], "main.work") == nil || len(data.RawPprof.Function) != 3 {
+		t.Error("the loaded profile should not be modified")
+	}
+
+	unpruned := parseTestProfile(t, newTestProfile(t))
+	if got := pruneProfile(unpruned.RawPprof, 0, unpruned.Views[0]); got != unpruned.RawPprof {
+		t.Error("a profile without pruned functions should be returned as is")
+	}
+}
+
+func TestMergePprofFiles(t *testing.T) {
+	var readers []io.Reader
+	for i := 0; i < 3; i++ {
+		p := newTestProfile(t)
+		p.DurationNanos = 1e9
+		readers = append(readers, writeTestProfile(t, p))
+	}
+
+	data, err := MergePprofFiles(readers, nil)
+	if err != nil {
+		t.Fatalf("MergePprofFiles: %v", err)
+	}
+	if data.MergedCount != 3 {
+		t.Errorf("MergedCount = %d, want 3", data.MergedCount)
+	}
+	if data.DurationNanos != 3e9 {
+		t.Errorf("DurationNanos = %d, want combined 3s", data.DurationNanos)
+	}
+	if total := data.Views[0].TotalValue; total != 165 {
+		t.Errorf("TotalValue = %d, want 165", total)
+	}
+	if helper := findNode(data.Views[0], "main.helper"); helper == nil || helper.FlatValue != 150 {
+		t.Errorf("main.helper should have merged flat value 150")
+	}
+}
+
+func writeTestProfile(t *testing.T, p *profile.Profile) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("writing profile: %v", err)
+	}
+	return &buf
+}
+
+func TestDiffPprofFilesEdges(t *testing.T) {
+	before := newTestProfile(t)
+	after := newTestProfile(t)
+	after.Sample[0].Value[0] = 60   // main.work:10 -> main.helper doubles
+	after.Sample = after.Sample[:2] // main.work's own samples disappear
+
+	data, err := DiffPprofFiles(writeTestProfile(t, before), writeTestProfile(t, after), nil)
+	if err != nil {
+		t.Fatalf("DiffPprofFiles: %v", err)
+	}
+	view := data.Views[0]
+	work, helper, main := findNode(view, "main.work"), findNode(view, "main.helper"), findNode(view, "main.main")
+
+	if got := work.Out[helper]; got != 30 {
+		t.Errorf("main.work -> main.helper delta = %d, want +30", got)
+	}
+	if got := helper.In[work]; got != 30 {
+		t.Errorf("main.helper caller delta = %d, want +30", got)
+	}
+	if got := main.Out[work]; got != 25 {
+		t.Errorf("main.main -> main.work delta = %d, want +25", got)
+	}
+}
+
+func TestBuildDiffFlameGraph(t *testing.T) {
+	before := newTestProfile(t)
+	after := newTestProfile(t)
+	after.Sample[0].Value[0] = 60   // main.work -> main.helper grows by 30
+	after.Sample = after.Sample[:2] // main.work's own samples disappear
+
+	root := BuildDiffFlameGraph(before, after, "samples", false)
+	work := findNodeByName(root, "main.work")
+	helper := findNodeByName(root, "main.helper")
+	if work == nil || helper == nil {
+		t.Fatal("expected main.work and main.helper frames")
+	}
+	if work.BeforeValue != 55 || work.AfterValue != 80 {
+		t.Errorf("main.work before/after = %d/%d, want 55/80", work.BeforeValue, work.AfterValue)
+	}
+	if helper.BeforeValue != 50 || helper.AfterValue != 80 {
+		t.Errorf("main.helper before/after = %d/%d, want 50/80", helper.BeforeValue, helper.AfterValue)
+	}
+	if root.Value != 80 || work.Value != 80 {
+		t.Errorf("widths should follow the after profile, got root=%d work=%d", root.Value, work.Value)
+	}
+
+	setFlameWidthBasis(root, true)
+	if root.Value != 55 || work.Value != 55 {
+		t.Errorf("widths should follow the before profile, got root=%d work=%d", root.Value, work.Value)
+	}
+	if got := formatFlameDelta(helper); got != "+60.0%" {
+		t.Errorf("formatFlameDelta(main.helper) = %q, want +60.0%%", got)
+	}
+}
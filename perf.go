@@ -0,0 +1,173 @@
+// perf.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// Labels attached to every sample of a perf script.
+const (
+	perfCommLabel = "comm"
+	perfPIDLabel  = "pid"
+	perfTIDLabel  = "tid"
+)
+
+var (
+	// perfHeaderRE matches a sample header such as
+	// "myproc  1234/1235 [002] 12345.678901:     250000 cpu-clock:pppH:".
+	// The pid, CPU and period fields depend on the fields perf was asked to print.
+	perfHeaderRE = regexp.MustCompile(`^(\S.*?)\s+(\d+)(?:/(\d+))?\s+(?:\[\d+\]\s+)?\d+\.\d+:\s+(?:(\d+)\s+)?(\S+?):?\s*$`)
+	// perfFrameRE matches a stack frame such as
+	// "	    55d1c2a3b4c5 main.work+0x25 (/usr/bin/myproc)".
+	perfFrameRE  = regexp.MustCompile(`^\s+([0-9a-f]+)\s+(.*?)\s*\(([^()]*)\)$`)
+	perfOffsetRE = regexp.MustCompile(`\+0x[0-9a-f]+$`)
+)
+
+// isPerfScript reports whether data looks like the output of `perf script`.
+func isPerfScript(data []byte) bool {
+	lines := leadingLines(data, sniffLines)
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 || line[0] == '#' {
+			continue
+		}
+		return perfHeaderRE.Match(line) && i+1 < len(lines) && perfFrameRE.Match(lines[i+1])
+	}
+	return false
+}
+
+// ParsePerfScript converts `perf script` output into a profile. Every sample
+// carries the process name, pid and tid as labels. Frames are named after their
+// symbol without the offset, so kernel, cgo and Go frames all become functions.
+// When perf printed periods, each event gets its own sample type weighed by
+// them, so captures of several events such as cycles and instructions are
+// never summed together.
+func ParsePerfScript(data []byte) (*profile.Profile, error) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "samples", Unit: "count"},
+		Period:     1,
+	}
+	mappings := make(map[string]*profile.Mapping)
+	functions := make(map[[2]string]*profile.Function)
+	locations := make(map[[2]string]*profile.Location)
+	events := make(map[string]int) // Sample type index of each event.
+
+	var current *profile.Sample
+	var period int64
+	var eventIndex int
+	flush := func() {
+		if current != nil && len(current.Location) > 0 {
+			current.Value = make([]int64, len(p.SampleType))
+			current.Value[0] = 1
+			if eventIndex > 0 {
+				current.Value[eventIndex] = period
+			}
+			p.Sample = append(p.Sample, current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			flush()
+			continue
+		}
+		if m := perfFrameRE.FindStringSubmatch(line); m != nil && current != nil {
+			addr, _ := strconv.ParseUint(m[1], 16, 64)
+			dso := m[3]
+			locKey := [2]string{dso, m[1]}
+			loc, ok := locations[locKey]
+			if !ok {
+				mapping, ok := mappings[dso]
+				if !ok {
+					mapping = &profile.Mapping{ID: uint64(len(p.Mapping) + 1), File: dso}
+					mappings[dso] = mapping
+					p.Mapping = append(p.Mapping, mapping)
+				}
+				name := perfOffsetRE.ReplaceAllString(m[2], "")
+				if name == "" || name == "[unknown]" {
+					name = fmt.Sprintf("[unknown] (%s)", filepath.Base(dso))
+				}
+				fnKey := [2]string{name, dso}
+				fn, ok := functions[fnKey]
+				if !ok {
+					fn = &profile.Function{ID: uint64(len(p.Function) + 1), Name: name, SystemName: name}
+					functions[fnKey] = fn
+					p.Function = append(p.Function, fn)
+				}
+				loc = &profile.Location{
+					ID:      uint64(len(p.Location) + 1),
+					Mapping: mapping,
+					Address: addr,
+					Line:    []profile.Line{{Function: fn}},
+				}
+				locations[locKey] = loc
+				p.Location = append(p.Location, loc)
+			}
+			current.Location = append(current.Location, loc)
+			continue
+		}
+		m := perfHeaderRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		flush()
+		current = &profile.Sample{
+			Label:    map[string][]string{perfCommLabel: {m[1]}},
+			NumLabel: make(map[string][]int64),
+		}
+		// perf prints "pid/tid" when asked for both, otherwise just the tid.
+		tid, _ := strconv.ParseInt(m[2], 10, 64)
+		if m[3] != "" {
+			current.NumLabel[perfPIDLabel] = []int64{tid}
+			tid, _ = strconv.ParseInt(m[3], 10, 64)
+		}
+		current.NumLabel[perfTIDLabel] = []int64{tid}
+		period, eventIndex = 0, 0
+		if m[4] != "" {
+			period, _ = strconv.ParseInt(m[4], 10, 64)
+			event := perfEventType(m[5])
+			i, ok := events[event.Type]
+			if !ok {
+				i = len(p.SampleType)
+				events[event.Type] = i
+				p.SampleType = append(p.SampleType, event)
+			}
+			eventIndex = i
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.Sample) == 0 {
+		return nil, fmt.Errorf("no samples with stacks found; record with `perf record -g`")
+	}
+	// Samples read before a later event was first seen have no value for it.
+	for _, s := range p.Sample {
+		s.Value = append(s.Value, make([]int64, len(p.SampleType)-len(s.Value))...)
+	}
+	return p, p.CheckValid()
+}
+
+// perfEventType names the sample type holding the event period. Software
+// clock events count nanoseconds; hardware events like cycles are plain counts.
+func perfEventType(event string) *profile.ValueType {
+	name, _, _ := strings.Cut(event, ":")
+	unit := "count"
+	if name == "cpu-clock" || name == "task-clock" {
+		unit = "nanoseconds"
+	}
+	return &profile.ValueType{Type: name, Unit: unit}
+}
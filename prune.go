@@ -0,0 +1,205 @@
+// prune.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// pruneFractionSteps are the thresholds the user can step through interactively.
+var pruneFractionSteps = []float64{0, 0.0005, 0.001, 0.005, 0.01, 0.05}
+
+// PruneOptions drop insignificant nodes and edges from large profiles, like
+// pprof's -nodefraction and -edgefraction.
+type PruneOptions struct {
+	NodeFraction float64 // Drop functions whose cum is below this fraction of the total.
+	EdgeFraction float64 // Drop call edges whose weight is below this fraction of the total.
+}
+
+// IsActive reports whether any pruning threshold is set.
+func (o PruneOptions) IsActive() bool {
+	return o.NodeFraction > 0 || o.EdgeFraction > 0
+}
+
+// String summarizes the thresholds for the header.
+func (o PruneOptions) String() string {
+	var parts []string
+	if o.NodeFraction > 0 {
+		parts = append(parts, fmt.Sprintf("nodes < %g%%", o.NodeFraction*100))
+	}
+	if o.EdgeFraction > 0 {
+		parts = append(parts, fmt.Sprintf("edges < %g%%", o.EdgeFraction*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stepFraction moves a threshold to the next (step > 0) or previous (step < 0) preset.
+func stepFraction(current float64, step int) float64 {
+	i := 0
+	for i < len(pruneFractionSteps)-1 && pruneFractionSteps[i] < current {
+		i++
+	}
+	i += step
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(pruneFractionSteps) {
+		i = len(pruneFractionSteps) - 1
+	}
+	return pruneFractionSteps[i]
+}
+
+// pruneProfileData applies the thresholds to every view. Pruned functions are
+// folded into a single "(pruned N functions)" node that keeps their flat values
+// and call edges, so the flat values of a view still add up to its total.
+// Diff views are never pruned, so values are never negative.
+func pruneProfileData(data *ProfileData, opts PruneOptions) {
+	if data == nil || !opts.IsActive() {
+		return
+	}
+	for _, view := range data.Views {
+		total := float64(view.TotalValue)
+		if opts.NodeFraction > 0 {
+			pruneNodes(view, int64(math.Ceil(total*opts.NodeFraction)))
+		}
+		if opts.EdgeFraction > 0 {
+			pruneEdges(view, int64(math.Ceil(total*opts.EdgeFraction)))
+		}
+	}
+}
+
+func pruneNodes(view *ProfileView, threshold int64) {
+	pruned := make(map[*FuncNode]struct{})
+	for _, node := range view.Nodes {
+		if node.CumValue < threshold {
+			pruned[node] = struct{}{}
+		}
+	}
+	if len(pruned) == 0 {
+		return
+	}
+
+	name := prunedName(len(pruned))
+	folded := &FuncNode{
+		ID:   hashString(name),
+		Name: name,
+		In:   make(map[*FuncNode]int64),
+		Out:  make(map[*FuncNode]int64),
+	}
+	for node := range pruned {
+		delete(view.Nodes, node.ID)
+		folded.FlatValue += node.FlatValue
+
+		// Re-attach edges between kept and pruned functions to the folded node.
+		// Edges between two pruned functions disappear inside it.
+		for caller, weight := range node.In {
+			if _, isPruned := pruned[caller]; isPruned {
+				continue
+			}
+			delete(caller.Out, node)
+			caller.Out[folded] += weight
+			folded.In[caller] += weight
+		}
+		for callee, weight := range node.Out {
+			if _, isPruned := pruned[callee]; isPruned {
+				continue
+			}
+			delete(callee.In, node)
+			callee.In[folded] += weight
+			folded.Out[callee] += weight
+		}
+	}
+	// The folded node stands for the pruned functions' own work.
+	folded.CumValue = folded.FlatValue
+	view.Nodes[folded.ID] = folded
+}
+
+func pruneEdges(view *ProfileView, threshold int64) {
+	for _, node := range view.Nodes {
+		for callee, weight := range node.Out {
+			if weight < threshold {
+				delete(node.Out, callee)
+				delete(callee.In, node)
+			}
+		}
+	}
+}
+
+// prunedName names the entry that n pruned functions are folded into.
+func prunedName(n int) string {
+	if n == 1 {
+		return "(pruned 1 function)"
+	}
+	return fmt.Sprintf("(pruned %d functions)", n)
+}
+
+// pruneProfile returns a copy of p in which the functions that node pruning
+// removed from view are folded into one "(pruned N functions)" function, so
+// that a saved profile shows what the view shows. Pruned edges have no
+// equivalent in samples and are kept.
+func pruneProfile(p *profile.Profile, sampleIndex int, view *ProfileView) *profile.Profile {
+	if p == nil || sampleIndex >= len(p.SampleType) {
+		return p
+	}
+	pruned := make(map[uint64]struct{})
+	for _, s := range p.Sample {
+		if s.Value[sampleIndex] == 0 {
+			continue
+		}
+		for _, loc := range s.Location {
+			for _, line := range loc.Line {
+				if _, kept := view.Nodes[line.Function.ID]; !kept {
+					pruned[line.Function.ID] = struct{}{}
+				}
+			}
+		}
+	}
+	if len(pruned) == 0 {
+		return p
+	}
+
+	out := p.Copy()
+	var nextID uint64
+	functions := out.Function[:0]
+	for _, fn := range out.Function {
+		nextID = max(nextID, fn.ID)
+		if _, isPruned := pruned[fn.ID]; !isPruned {
+			functions = append(functions, fn)
+		}
+	}
+	name := prunedName(len(pruned))
+	folded := &profile.Function{ID: nextID + 1, Name: name, SystemName: name}
+	out.Function = append(functions, folded)
+
+	isFolded := func(loc *profile.Location) bool {
+		return len(loc.Line) == 1 && loc.Line[0].Function == folded
+	}
+	for _, loc := range out.Location {
+		lines := loc.Line[:0]
+		for _, line := range loc.Line {
+			if _, isPruned := pruned[line.Function.ID]; isPruned {
+				if n := len(lines); n > 0 && lines[n-1].Function == folded {
+					continue
+				}
+				line = profile.Line{Function: folded}
+			}
+			lines = append(lines, line)
+		}
+		loc.Line = lines
+	}
+	// Calls between pruned functions disappear inside the folded one.
+	for _, s := range out.Sample {
+		locations := s.Location[:0]
+		for _, loc := range s.Location {
+			if n := len(locations); n > 0 && isFolded(loc) && isFolded(locations[n-1]) {
+				continue
+			}
+			locations = append(locations, loc)
+		}
+		s.Location = locations
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+// series.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// sparkBlocks are the glyphs of a sparkline, from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// ParsePprofSeries parses an ordered series of profiles, such as hourly heap
+// snapshots, oldest first.
+func ParsePprofSeries(readers []io.Reader, symbolizer *Symbolizer) ([]*profile.Profile, error) {
+	if len(readers) < 2 {
+		return nil, fmt.Errorf("a series needs at least two profiles")
+	}
+	profiles := make([]*profile.Profile, 0, len(readers))
+	for i, reader := range readers {
+		p, err := parseProfile(reader, symbolizer)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse profile %d: %w", i+1, err)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// NewSeriesData builds the views of the latest profile of a series and records,
+// for every function of any profile in it, its values across the whole series.
+// Functions missing from the latest profile are listed with zero values.
+func NewSeriesData(profiles []*profile.Profile) (*ProfileData, error) {
+	snapshots := make([]*ProfileData, 0, len(profiles))
+	for i, p := range profiles {
+		snapshot, err := NewProfileData(p)
+		if err != nil {
+			return nil, fmt.Errorf("profile %d: %w", i+1, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	data := snapshots[len(snapshots)-1]
+	data.SeriesLength = len(snapshots)
+
+	for _, view := range data.Views {
+		addVanishedNodes(view, snapshots[:len(snapshots)-1])
+		index := indexRuns(snapshots, view.SampleType)
+		for _, node := range view.Nodes {
+			sig := funcSignature(node)
+			node.Series = make([]LineCost, len(index))
+			cum := make([]float64, len(index))
+			for i, costs := range index {
+				node.Series[i] = costs[sig]
+				cum[i] = float64(costs[sig].Cum)
+			}
+			node.Slope = linearSlope(cum)
+		}
+	}
+	return data, nil
+}
+
+// addVanishedNodes adds the functions that only appear in earlier snapshots to
+// a view of the latest one, so that the trend of a function that went away is
+// not lost. They have no value, calls or line costs of their own.
+func addVanishedNodes(view *ProfileView, earlier []*ProfileData) {
+	present := make(map[string]struct{}, len(view.Nodes))
+	for _, node := range view.Nodes {
+		present[funcSignature(node)] = struct{}{}
+	}
+	for _, snapshot := range earlier {
+		for _, old := range snapshot.Views {
+			if old.SampleType != view.SampleType {
+				continue
+			}
+			for _, node := range old.Nodes {
+				sig := funcSignature(node)
+				if _, ok := present[sig]; ok {
+					continue
+				}
+				present[sig] = struct{}{}
+				// Function IDs are only unique within one profile.
+				id := hashString(sig)
+				view.Nodes[id] = &FuncNode{
+					ID:        id,
+					Name:      node.Name,
+					FileName:  node.FileName,
+					StartLine: node.StartLine,
+					Lines:     make(map[int]*LineCost),
+					In:        make(map[*FuncNode]int64),
+					Out:       make(map[*FuncNode]int64),
+				}
+			}
+		}
+	}
+}
+
+// linearSlope returns the least-squares slope of the values against their
+// position in the series, i.e. the average change per profile.
+func linearSlope(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// sparkline renders values as a row of block glyphs scaled between zero and the largest value.
+func sparkline(values []int64) string {
+	var peak int64
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if peak > 0 && v > 0 {
+			level = int(float64(v) / float64(peak) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// formatSeriesTrend summarizes a function's flat and cumulative values over
+// the series, e.g. "self ▁▁▂▄ · total ▁▂▅█ · +1.2MB/profile".
+func formatSeriesTrend(node *FuncNode, unit string) string {
+	flat := make([]int64, len(node.Series))
+	cum := make([]int64, len(node.Series))
+	for i, cost := range node.Series {
+		flat[i] = cost.Flat
+		cum[i] = cost.Cum
+	}
+	return fmt.Sprintf("self %s · total %s · %s/profile",
+		sparkline(flat), sparkline(cum), formatSignedValue(int64(node.Slope), unit))
+}
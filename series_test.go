@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestNewSeriesData(t *testing.T) {
+	var profiles []*profile.Profile
+	for i := int64(0); i < 4; i++ {
+		p := newTestProfile(t)
+		p.Sample[0].Value[0] = 30 + 10*i // main.helper leaks 10 per snapshot
+		profiles = append(profiles, p)
+	}
+
+	data, err := NewSeriesData(profiles)
+	if err != nil {
+		t.Fatalf("NewSeriesData: %v", err)
+	}
+	if data.SeriesLength != 4 {
+		t.Errorf("SeriesLength = %d, want 4", data.SeriesLength)
+	}
+	view := data.Views[0]
+	helper := findNode(view, "main.helper")
+	if helper.CumValue != 80 {
+		t.Errorf("views should show the latest profile, main.helper cum = %d, want 80", helper.CumValue)
+	}
+	if len(helper.Series) != 4 || helper.Series[0].Cum != 50 || helper.Series[3].Cum != 80 {
+		t.Errorf("main.helper series = %v, want cum 50..80", helper.Series)
+	}
+	if helper.Slope != 10 {
+		t.Errorf("main.helper slope = %v, want 10", helper.Slope)
+	}
+	if work := findNode(view, "main.work"); work.Slope != helper.Slope {
+		t.Errorf("main.work only grows through main.helper, slope = %v", work.Slope)
+	}
+}
+
+func TestNewSeriesDataKeepsVanishedFunctions(t *testing.T) {
+	var profiles []*profile.Profile
+	for i := int64(0); i < 3; i++ {
+		p := newTestProfile(t)
+		p.Sample[0].Value[0] = 10 * (i + 1)
+		profiles = append(profiles, p)
+	}
+	// main.helper is gone from the last snapshot, e.g. after its cache was dropped.
+	last := profiles[2]
+	last.Sample = last.Sample[2:]
+	// Function IDs of different profiles need not match.
+	for _, fn := range last.Function {
+		fn.ID += 100
+	}
+
+	data, err := NewSeriesData(profiles)
+	if err != nil {
+		t.Fatalf("NewSeriesData: %v", err)
+	}
+	view := data.Views[0]
+	helper := findNode(view, "main.helper")
+	if helper == nil {
+		t.Fatal("main.helper should be listed although the last snapshot lacks it")
+	}
+	if helper.FlatValue != 0 || helper.CumValue != 0 {
+		t.Errorf("main.helper = flat %d, cum %d in the last snapshot, want 0", helper.FlatValue, helper.CumValue)
+	}
+	want := []int64{30, 40, 0} // 10, 20, 30 from line 10 plus 20 from line 12
+	if len(helper.Series) != 3 {
+		t.Fatalf("main.helper series = %v, want 3 points", helper.Series)
+	}
+	for i, cost := range helper.Series {
+		if cost.Cum != want[i] {
+			t.Errorf("main.helper cum in snapshot %d = %d, want %d", i, cost.Cum, want[i])
+		}
+	}
+	if helper.Slope >= 0 {
+		t.Errorf("main.helper slope = %v, want a decline", helper.Slope)
+	}
+	if len(view.Nodes) != 3 {
+		t.Errorf("view has %d functions, want 3", len(view.Nodes))
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int64{0, 1, 2, 4, 8}); got != "▁▁▂▄█" {
+		t.Errorf("sparkline = %q", got)
+	}
+	if got := sparkline([]int64{0, 0}); got != "▁▁" {
+		t.Errorf("sparkline of zeros = %q", got)
+	}
+}
@@ -4,19 +4,22 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2/quick"
 )
 
 // getHighlightedSource reads a file, highlights it, and adds line numbers and an arrow.
-func getHighlightedSource(filePath string, targetLine int) string {
+// When per-line costs are given, a flat/cum gutter is drawn next to each line,
+// in the style of `go tool pprof -list`. The file is looked up with sources,
+// which maps the paths of the build machine to local ones, using the function
+// name for files recorded without a usable path.
+func getHighlightedSource(sources *SourceLocator, filePath, funcName string, targetLine int, lineCosts map[int]*LineCost, unit string) string {
 	if filePath == "" {
 		return "No source file available."
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, err := sources.ReadFile(filePath, funcName)
 	if err != nil {
 		return fmt.Sprintf("Error reading file %s:\n%v", filePath, err)
 	}
@@ -39,8 +42,29 @@ func getHighlightedSource(filePath string, targetLine int) string {
 			// Add an arrow to the target line
 			lineHeader = "  -> | "
 		}
+		if len(lineCosts) > 0 {
+			lineHeader = formatLineCostGutter(lineCosts[lineNumber], unit) + lineHeader
+		}
 		result.WriteString(lineHeader + line + "\n")
 	}
 
 	return result.String()
 }
+
+// formatLineCostGutter renders the flat and cum columns for a single source line.
+// Lines without any samples get an empty gutter so the code stays aligned.
+func formatLineCostGutter(cost *LineCost, unit string) string {
+	const columnWidth = 10
+	if cost == nil {
+		return strings.Repeat(" ", 2*columnWidth+1) + " "
+	}
+	flat := "."
+	if cost.Flat != 0 {
+		flat = formatValue(cost.Flat, unit)
+	}
+	cum := "."
+	if cost.Cum != 0 {
+		cum = formatValue(cost.Cum, unit)
+	}
+	return fmt.Sprintf("%*s %*s ", columnWidth, flat, columnWidth, cum)
+}
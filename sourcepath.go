@@ -0,0 +1,395 @@
+// sourcepath.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// SourceLocator finds the local copy of the source files a profile refers to.
+// Profiles of deployed binaries record the paths of the machine that built
+// them, like /build/src/github.com/org/svc/x.go or /go/pkg/mod/..., which
+// rarely exist where the profile is looked at.
+type SourceLocator struct {
+	TrimPrefixes []string          // Build path prefixes to remove, like pprof's -trim_path.
+	SearchDirs   []string          // Directories to look for sources in, like pprof's -source_path.
+	RepoRoot     string            // Root of the repository pproftui runs in, searched last.
+	ModulePath   string            // Module path declared by RepoRoot's go.mod, if any.
+	Requires     map[string]string // Module versions required by RepoRoot's go.mod.
+	GOROOT       string            // Local Go installation, for standard library sources.
+	ModCache     string            // Local module cache, for dependency sources.
+	Revision     string            // Git revision of RepoRoot the profiled binary was built from.
+
+	cache     map[string]string // Profile path to local path, "" when not found.
+	revisions map[string][]byte // Local path to its content at Revision, nil when not in it.
+	drifted   map[string]bool   // Local path to whether it changed since Revision.
+}
+
+// NewSourceLocator creates a locator from the --trim-path and --source-path
+// flags, both lists separated like $PATH, the repository containing the
+// current directory and the local Go installation.
+func NewSourceLocator(trimPath, sourcePath string) *SourceLocator {
+	l := &SourceLocator{
+		TrimPrefixes: filepath.SplitList(trimPath),
+		SearchDirs:   filepath.SplitList(sourcePath),
+		cache:        make(map[string]string),
+	}
+	if wd, err := os.Getwd(); err == nil {
+		l.RepoRoot = findRepoRoot(wd)
+		l.ModulePath, l.Requires = readGoMod(filepath.Join(l.RepoRoot, "go.mod"))
+	}
+	l.GOROOT, l.ModCache = goEnv()
+	return l
+}
+
+// goEnv returns the GOROOT and GOMODCACHE of the local Go installation, asking
+// the go command when it is installed and falling back to the environment.
+func goEnv() (goroot, modCache string) {
+	if out, err := exec.Command("go", "env", "GOROOT", "GOMODCACHE").Output(); err == nil {
+		if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); len(lines) == 2 {
+			return lines[0], lines[1]
+		}
+	}
+	goroot = os.Getenv("GOROOT")
+	modCache = os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		gopath := filepath.SplitList(os.Getenv("GOPATH"))
+		if len(gopath) > 0 && gopath[0] != "" {
+			modCache = filepath.Join(gopath[0], "pkg", "mod")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			modCache = filepath.Join(home, "go", "pkg", "mod")
+		}
+	}
+	return goroot, modCache
+}
+
+// findRepoRoot returns the closest directory above dir containing .git, or
+// else go.mod. It returns dir itself when there is neither.
+func findRepoRoot(dir string) string {
+	modRoot := ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil && modRoot == "" {
+			modRoot = d
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if modRoot != "" {
+		return modRoot
+	}
+	return dir
+}
+
+// readGoMod returns the module path declared by a go.mod file and the
+// versions of the modules it requires. Both are empty if there is no go.mod.
+func readGoMod(goMod string) (string, map[string]string) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+	modulePath := ""
+	requires := make(map[string]string)
+	inRequire := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire && len(fields) >= 2:
+			requires[strings.Trim(fields[0], `"`)] = fields[1]
+		case fields[0] == "module" && len(fields) >= 2:
+			modulePath = strings.Trim(fields[1], `"`)
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) >= 3:
+			requires[strings.Trim(fields[1], `"`)] = fields[2]
+		}
+	}
+	return modulePath, requires
+}
+
+// Locate returns the local path of a source file recorded in a profile for
+// the named function, or "" if it cannot be found. Paths that exist are used
+// as is. Otherwise the trim prefixes are removed and the rest is looked up in
+// the search directories, files of the repository's own module are looked up
+// under its root, and standard library and dependency files in GOROOT and the
+// module cache. As a last resort the longest suffix of the path found in one
+// of the directories wins.
+func (l *SourceLocator) Locate(path, funcName string) string {
+	if path == "" {
+		return ""
+	}
+	if local, ok := l.cache[path]; ok {
+		return local
+	}
+	local := l.locate(path, funcName)
+	l.cache[path] = local
+	return local
+}
+
+func (l *SourceLocator) locate(path, funcName string) string {
+	if fileExists(path) {
+		return path
+	}
+	dirs := l.SearchDirs
+	if l.RepoRoot != "" {
+		dirs = append(dirs[:len(dirs):len(dirs)], l.RepoRoot)
+	}
+
+	for _, prefix := range l.TrimPrefixes {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		rel := strings.TrimLeft(strings.TrimPrefix(path, prefix), "/")
+		for _, dir := range dirs {
+			if candidate := filepath.Join(dir, rel); fileExists(candidate) {
+				return candidate
+			}
+		}
+	}
+
+	if l.ModulePath != "" {
+		if _, rel, ok := strings.Cut(filepath.ToSlash(path), l.ModulePath+"/"); ok {
+			if candidate := filepath.Join(l.RepoRoot, rel); fileExists(candidate) {
+				return candidate
+			}
+		}
+	}
+
+	if local := l.locateGoSource(path, funcName); local != "" {
+		return local
+	}
+
+	// A bare file name says too little to tell e.g. two main.go apart, so
+	// suffixes keep at least one directory.
+	parts := strings.Split(strings.TrimLeft(filepath.ToSlash(path), "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		suffix := filepath.Join(parts[i:]...)
+		for _, dir := range dirs {
+			if candidate := filepath.Join(dir, suffix); fileExists(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// locateGoSource finds standard library files in GOROOT and dependency files
+// in the module cache. Paths normally say where they were: under GOROOT/src,
+// under a module cache as module@version, or, with -trimpath, relative to
+// either. Paths that only say the package, like vendored ones, are found from
+// the function's package and the version the repository's go.mod requires.
+func (l *SourceLocator) locateGoSource(path, funcName string) string {
+	slash := filepath.ToSlash(path)
+	if l.GOROOT != "" {
+		rest := slash
+		for {
+			if !filepath.IsAbs(rest) {
+				if candidate := filepath.Join(l.GOROOT, "src", rest); fileExists(candidate) {
+					return candidate
+				}
+			}
+			_, after, ok := strings.Cut(rest, "/src/")
+			if !ok {
+				break
+			}
+			rest = after
+		}
+	}
+	if l.ModCache == "" {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimLeft(slash, "/"), "/")
+	at := -1
+	for i, part := range parts {
+		if strings.Contains(part, "@") {
+			at = i
+			break
+		}
+	}
+	for i := 0; i <= at; i++ {
+		module := escapeModulePath(strings.Join(parts[i:at+1], "/"))
+		candidate := filepath.Join(l.ModCache, module, filepath.Join(parts[at+1:]...))
+		if fileExists(candidate) {
+			return candidate
+		}
+	}
+
+	pkg := funcPackage(funcName)
+	if pkg == "" {
+		return ""
+	}
+	if first, _, _ := strings.Cut(pkg, "/"); !strings.Contains(first, ".") {
+		candidate := filepath.Join(l.GOROOT, "src", pkg, filepath.Base(path))
+		if l.GOROOT != "" && fileExists(candidate) {
+			return candidate
+		}
+		return ""
+	}
+	// The longest required module path that contains the package.
+	module := ""
+	for required := range l.Requires {
+		if (pkg == required || strings.HasPrefix(pkg, required+"/")) && len(required) > len(module) {
+			module = required
+		}
+	}
+	if module == "" {
+		return ""
+	}
+	dir := escapeModulePath(module + "@" + l.Requires[module])
+	candidate := filepath.Join(l.ModCache, dir, strings.TrimPrefix(pkg, module), filepath.Base(path))
+	if fileExists(candidate) {
+		return candidate
+	}
+	return ""
+}
+
+// funcPackage returns the import path of the package a Go function belongs
+// to, e.g. "encoding/json" for "encoding/json.(*decodeState).object". Symbol
+// names escape dots in the last path element, as in "gopkg.in/yaml%2ev3.Unmarshal".
+func funcPackage(funcName string) string {
+	funcName, _, _ = strings.Cut(funcName, "[") // Type arguments may contain paths too.
+	slash := strings.LastIndex(funcName, "/")
+	dot := strings.Index(funcName[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	pkg := funcName[:slash+1+dot]
+	if strings.ContainsAny(pkg, " ()<>:*") {
+		return "" // Not a Go symbol, e.g. a C++ one.
+	}
+	return strings.ReplaceAll(pkg, "%2e", ".")
+}
+
+// escapeModulePath applies the module cache's case encoding, which writes
+// upper case letters as '!' and the lower case letter.
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ReadFile reads a source file recorded in a profile for the named function
+// from its local copy, as of Revision when one is set and the file is part of
+// the repository. A nil locator reads the path as is.
+func (l *SourceLocator) ReadFile(path, funcName string) ([]byte, error) {
+	if l == nil {
+		return os.ReadFile(path)
+	}
+	local := l.Locate(path, funcName)
+	if content := l.readAtRevision(local); content != nil {
+		return content, nil
+	}
+	if local == "" {
+		return nil, fmt.Errorf("no local copy found; point --source-path at a checkout or --trim-path at the build directory")
+	}
+	return os.ReadFile(local)
+}
+
+// SetRevision makes the locator read repository files as of a git revision,
+// such as the commit a deployed binary was built from.
+func (l *SourceLocator) SetRevision(rev string) error {
+	out, err := exec.Command("git", "-C", l.RepoRoot, "rev-parse", "--verify", "--quiet", rev+"^{commit}").Output()
+	if err != nil {
+		return fmt.Errorf("revision %s not found in %s", rev, l.RepoRoot)
+	}
+	l.Revision = strings.TrimSpace(string(out))
+	l.revisions = make(map[string][]byte)
+	l.drifted = make(map[string]bool)
+	return nil
+}
+
+// ShortRevision returns Revision abbreviated like git does.
+func (l *SourceLocator) ShortRevision() string {
+	if l == nil || len(l.Revision) <= 7 {
+		return l.Revision
+	}
+	return l.Revision[:7]
+}
+
+// readAtRevision returns the content of a local repository file at Revision,
+// or nil if there is no revision or the file is not part of it.
+func (l *SourceLocator) readAtRevision(local string) []byte {
+	if l.Revision == "" || local == "" {
+		return nil
+	}
+	if content, ok := l.revisions[local]; ok {
+		return content
+	}
+	var content []byte
+	rel, err := filepath.Rel(l.RepoRoot, local)
+	if err == nil && !strings.HasPrefix(rel, "..") {
+		out, err := exec.Command("git", "-C", l.RepoRoot, "show", l.Revision+":"+filepath.ToSlash(rel)).Output()
+		if err == nil {
+			content = out
+		}
+	}
+	l.revisions[local] = content
+	return content
+}
+
+// Drifted reports whether the working tree copy of a source file differs from
+// the file at Revision, so the lines of today's file would not match the
+// profile's.
+func (l *SourceLocator) Drifted(path, funcName string) bool {
+	if l == nil || l.Revision == "" {
+		return false
+	}
+	local := l.Locate(path, funcName)
+	if drifted, ok := l.drifted[local]; ok {
+		return drifted
+	}
+	drifted := false
+	if old := l.readAtRevision(local); old != nil {
+		current, err := os.ReadFile(local)
+		drifted = err != nil || !bytes.Equal(old, current)
+	}
+	l.drifted[local] = drifted
+	return drifted
+}
+
+// buildRevisionRE matches the VCS revision in build information, as printed
+// by `go version -m` ("build	vcs.revision=4f3c2a1...").
+var buildRevisionRE = regexp.MustCompile(`vcs\.revision[=:\s]\s*([0-9a-fA-F]{7,40})\b`)
+
+// buildRevision returns the git revision recorded in a profile's comments, if
+// whoever captured it added the binary's build information.
+func buildRevision(p *profile.Profile) string {
+	if p == nil {
+		return ""
+	}
+	for _, comment := range p.Comments {
+		if m := buildRevisionRE.FindStringSubmatch(comment); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestSourceLocator(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	handler := writeFile("repo/internal/api/handler.go", "package api\n")
+	main := writeFile("repo/main.go", "package main\n")
+	vendored := writeFile("vendor-src/github.com/lib/pq/conn.go", "package pq\n")
+	decode := writeFile("goroot/src/encoding/json/decode.go", "package json\n")
+	toml := writeFile("modcache/github.com/!burnt!sushi/toml@v1.3.2/decode.go", "package toml\n")
+	yaml := writeFile("modcache/gopkg.in/yaml.v3@v3.0.1/decode.go", "package yaml\n")
+
+	l := &SourceLocator{
+		TrimPrefixes: []string{"/go/pkg/mod/"},
+		SearchDirs:   []string{filepath.Join(root, "vendor-src")},
+		RepoRoot:     filepath.Join(root, "repo"),
+		ModulePath:   "github.com/org/svc",
+		Requires:     map[string]string{"gopkg.in/yaml.v3": "v3.0.1"},
+		GOROOT:       filepath.Join(root, "goroot"),
+		ModCache:     filepath.Join(root, "modcache"),
+		cache:        make(map[string]string),
+	}
+	for _, tc := range []struct {
+		path, funcName, want string
+	}{
+		{handler, "", handler},
+		{"/build/src/github.com/org/svc/main.go", "main.main", main},
+		{"/go/pkg/mod/github.com/lib/pq/conn.go", "", vendored},
+		{"/workspace/checkout/internal/api/handler.go", "", handler},
+		{"/workspace/checkout/other/main.go", "", ""},
+		{"/opt/toolchain/src/encoding/json/decode.go", "encoding/json.(*decodeState).object", decode},
+		{"encoding/json/decode.go", "", decode},
+		{"/opt/toolchain/src/runtime/proc.go", "runtime.main", ""},
+		{"/home/ci/go/pkg/mod/github.com/!burnt!sushi/toml@v1.3.2/decode.go", "", toml},
+		{"github.com/BurntSushi/toml@v1.3.2/decode.go", "", toml},
+		{"/build/vendor/gopkg.in/yaml.v3/decode.go", "gopkg.in/yaml%2ev3.(*decoder).unmarshal", yaml},
+	} {
+		// Every case gets a fresh cache, since some paths are tried twice.
+		l.cache = make(map[string]string)
+		if got := l.Locate(tc.path, tc.funcName); got != tc.want {
+			t.Errorf("Locate(%s, %s) = %q, want %q", tc.path, tc.funcName, got, tc.want)
+		}
+	}
+
+	if _, err := l.ReadFile("/nowhere/x.go", ""); err == nil {
+		t.Error("ReadFile should fail for files without a local copy")
+	}
+}
+
+const testGoMod = `// comment
+module "example.com/svc"
+
+go 1.22
+
+require github.com/lib/pq v1.10.9
+
+require (
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sync v0.7.0
+)
+`
+
+func TestReadGoMod(t *testing.T) {
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(goMod, []byte(testGoMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modulePath, requires := readGoMod(goMod)
+	if modulePath != "example.com/svc" {
+		t.Errorf("module path = %q, want example.com/svc", modulePath)
+	}
+	if len(requires) != 3 || requires["github.com/lib/pq"] != "v1.10.9" || requires["gopkg.in/yaml.v3"] != "v3.0.1" {
+		t.Errorf("requires = %v", requires)
+	}
+}
+
+func TestSourceLocatorRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	file := filepath.Join(root, "work.go")
+	stable := filepath.Join(root, "stable.go")
+	git("init", "-q")
+	for path, content := range map[string]string{file: "package main\n\nfunc work() {}\n", stable: "package main\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "built")
+	if err := os.WriteFile(file, []byte("package main\n\n// New comment.\nfunc work() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &SourceLocator{RepoRoot: root, cache: make(map[string]string)}
+	if err := l.SetRevision("no-such-revision"); err == nil {
+		t.Error("SetRevision should reject unknown revisions")
+	}
+	if err := l.SetRevision("HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := l.ReadFile(file, "main.work")
+	if err != nil || string(content) != "package main\n\nfunc work() {}\n" {
+		t.Errorf("ReadFile = %q, %v; want the committed file", content, err)
+	}
+	if !l.Drifted(file, "main.work") {
+		t.Error("work.go changed since the revision and should be reported as drifted")
+	}
+	if l.Drifted(stable, "main.init") {
+		t.Error("stable.go did not change")
+	}
+}
+
+func TestBuildRevision(t *testing.T) {
+	p := &profile.Profile{Comments: []string{"built by ci", "build\tvcs.revision=4f3c2a1b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b"}}
+	if got := buildRevision(p); got != "4f3c2a1b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b" {
+		t.Errorf("buildRevision = %q", got)
+	}
+	if got := buildRevision(&profile.Profile{}); got != "" {
+		t.Errorf("buildRevision of a profile without comments = %q", got)
+	}
+}
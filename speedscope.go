@@ -0,0 +1,304 @@
+// speedscope.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/google/pprof/profile"
+)
+
+const (
+	speedscopeSchema   = "https://www.speedscope.app/file-format-schema.json"
+	speedscopeExporter = "pproftui"
+)
+
+// speedscopeTypeNameRE matches the "<type> (<unit>)" names WriteSpeedscope
+// gives its profiles, such as "alloc_space (bytes)".
+var speedscopeTypeNameRE = regexp.MustCompile(`^(\S+) \((\S+)\)$`)
+
+// speedscopeFile is speedscope's file format, see
+// https://github.com/jlfwong/speedscope/wiki/Importing-from-custom-sources.
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name,omitempty"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter,omitempty"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int64  `json:"line,omitempty"`
+	Col  int64  `json:"col,omitempty"`
+}
+
+// speedscopeProfile is either a "sampled" profile, a list of stacks with
+// weights, or an "evented" one, a sequence of frame open and close events.
+type speedscopeProfile struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit"`
+	StartValue float64           `json:"startValue"`
+	EndValue   float64           `json:"endValue"`
+	Samples    [][]int           `json:"samples"` // Frame indexes, root first.
+	Weights    []float64         `json:"weights"` // One per sample.
+	Events     []speedscopeEvent `json:"events,omitempty"`
+}
+
+type speedscopeEvent struct {
+	Type  string  `json:"type"` // "O" opens a frame, "C" closes it.
+	Frame int     `json:"frame"`
+	At    float64 `json:"at"`
+}
+
+// speedscopeProfileLabel records which speedscope profile, usually a thread,
+// a sample came from.
+const speedscopeProfileLabel = "profile"
+
+// isSpeedscope reports whether data looks like a speedscope JSON file.
+func isSpeedscope(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+	return bytes.Contains(data, []byte(`"profiles"`)) && bytes.Contains(data, []byte(`"shared"`))
+}
+
+// speedscopeValueType maps a speedscope unit to a pprof sample type, and the
+// factor that converts its values into the sample type's unit.
+func speedscopeValueType(unit string) (*profile.ValueType, float64, error) {
+	switch unit {
+	case "nanoseconds":
+		return &profile.ValueType{Type: "time", Unit: "nanoseconds"}, 1, nil
+	case "microseconds":
+		return &profile.ValueType{Type: "time", Unit: "nanoseconds"}, 1e3, nil
+	case "milliseconds":
+		return &profile.ValueType{Type: "time", Unit: "nanoseconds"}, 1e6, nil
+	case "seconds":
+		return &profile.ValueType{Type: "time", Unit: "nanoseconds"}, 1e9, nil
+	case "bytes":
+		return &profile.ValueType{Type: "space", Unit: "bytes"}, 1, nil
+	case "none", "":
+		return &profile.ValueType{Type: "samples", Unit: "count"}, 1, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported speedscope unit %q", unit)
+}
+
+// speedscopeSampleType is the sample type of a speedscope profile. Profiles
+// written by WriteSpeedscope are named after their pprof sample type, so that
+// e.g. inuse_space and alloc_space stay apart; any other profile gets the
+// sample type of its unit.
+func speedscopeSampleType(sp speedscopeProfile, exporter string) (*profile.ValueType, float64, error) {
+	vt, scale, err := speedscopeValueType(sp.Unit)
+	if err != nil || exporter != speedscopeExporter {
+		return vt, scale, err
+	}
+	m := speedscopeTypeNameRE.FindStringSubmatch(sp.Name)
+	if m == nil {
+		return vt, scale, nil
+	}
+	named := &profile.ValueType{Type: m[1], Unit: vt.Unit}
+	if speedscopeUnit(m[2]) == "none" {
+		// Units speedscope does not know, like "count", were written as "none".
+		named.Unit = m[2]
+	}
+	return named, scale, nil
+}
+
+// ParseSpeedscope converts a speedscope file into a profile. Every speedscope
+// profile in the file contributes samples labelled with its name, and profiles
+// with different sample types, see speedscopeSampleType, stay apart.
+func ParseSpeedscope(data []byte) (*profile.Profile, error) {
+	var file speedscopeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid speedscope file: %w", err)
+	}
+	if len(file.Profiles) == 0 {
+		return nil, fmt.Errorf("speedscope file has no profiles")
+	}
+
+	p := &profile.Profile{}
+	typeIndex := make(map[string]int)
+	for _, sp := range file.Profiles {
+		vt, _, err := speedscopeSampleType(sp, file.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := typeIndex[vt.Type]; !ok {
+			typeIndex[vt.Type] = len(p.SampleType)
+			p.SampleType = append(p.SampleType, vt)
+		}
+	}
+
+	// One location per shared frame, and one function per name and file.
+	functions := make(map[[2]string]*profile.Function)
+	locations := make([]*profile.Location, len(file.Shared.Frames))
+	for i, frame := range file.Shared.Frames {
+		key := [2]string{frame.Name, frame.File}
+		fn, ok := functions[key]
+		if !ok {
+			fn = &profile.Function{
+				ID:         uint64(len(p.Function) + 1),
+				Name:       frame.Name,
+				SystemName: frame.Name,
+				Filename:   frame.File,
+			}
+			functions[key] = fn
+			p.Function = append(p.Function, fn)
+		}
+		locations[i] = &profile.Location{
+			ID:   uint64(i + 1),
+			Line: []profile.Line{{Function: fn, Line: frame.Line}},
+		}
+	}
+	p.Location = locations
+
+	for _, sp := range file.Profiles {
+		vt, scale, _ := speedscopeSampleType(sp, file.Exporter)
+		idx := typeIndex[vt.Type]
+		addSample := func(stack []int, weight float64) error {
+			value := int64(weight * scale)
+			if value == 0 || len(stack) == 0 {
+				return nil
+			}
+			s := &profile.Sample{
+				Value: make([]int64, len(p.SampleType)),
+				Label: map[string][]string{speedscopeProfileLabel: {sp.Name}},
+			}
+			s.Value[idx] = value
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] < 0 || stack[i] >= len(locations) {
+					return fmt.Errorf("profile %q refers to unknown frame %d", sp.Name, stack[i])
+				}
+				s.Location = append(s.Location, locations[stack[i]])
+			}
+			p.Sample = append(p.Sample, s)
+			return nil
+		}
+
+		switch sp.Type {
+		case "sampled":
+			for i, stack := range sp.Samples {
+				weight := 1.0
+				if i < len(sp.Weights) {
+					weight = sp.Weights[i]
+				}
+				if err := addSample(stack, weight); err != nil {
+					return nil, err
+				}
+			}
+		case "evented":
+			// The time between two events is spent in the stack open at that point.
+			var stack []int
+			last := sp.StartValue
+			for _, ev := range sp.Events {
+				if err := addSample(stack, ev.At-last); err != nil {
+					return nil, err
+				}
+				last = ev.At
+				switch ev.Type {
+				case "O":
+					stack = append(stack, ev.Frame)
+				case "C":
+					if n := len(stack); n > 0 && stack[n-1] == ev.Frame {
+						stack = stack[:n-1]
+					} else {
+						return nil, fmt.Errorf("profile %q closes frame %d which is not open", sp.Name, ev.Frame)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported speedscope profile type %q", sp.Type)
+		}
+	}
+	if len(p.Sample) == 0 {
+		return nil, fmt.Errorf("speedscope file has no samples")
+	}
+	return p, p.CheckValid()
+}
+
+// WriteSpeedscope writes a profile as a speedscope file with one sampled
+// profile per sample type. Inlined calls become frames of their own.
+func WriteSpeedscope(w io.Writer, p *profile.Profile, name string) error {
+	file := speedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     name,
+		Exporter: speedscopeExporter,
+	}
+	frameIndex := make(map[*profile.Function]int)
+	frameOf := func(fn *profile.Function) int {
+		if i, ok := frameIndex[fn]; ok {
+			return i
+		}
+		i := len(file.Shared.Frames)
+		file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{
+			Name: fn.Name,
+			File: fn.Filename,
+			Line: fn.StartLine,
+		})
+		frameIndex[fn] = i
+		return i
+	}
+
+	for idx, st := range p.SampleType {
+		sp := speedscopeProfile{
+			Type: "sampled",
+			Name: fmt.Sprintf("%s (%s)", st.Type, st.Unit),
+			Unit: speedscopeUnit(st.Unit),
+			// speedscope requires both arrays on sampled profiles, even when empty.
+			Samples: [][]int{},
+			Weights: []float64{},
+		}
+		for _, s := range p.Sample {
+			value := s.Value[idx]
+			if value == 0 {
+				continue
+			}
+			var stack []int
+			for i := len(s.Location) - 1; i >= 0; i-- {
+				lines := s.Location[i].Line
+				for j := len(lines) - 1; j >= 0; j-- {
+					if lines[j].Function != nil {
+						stack = append(stack, frameOf(lines[j].Function))
+					}
+				}
+			}
+			sp.Samples = append(sp.Samples, stack)
+			sp.Weights = append(sp.Weights, float64(value))
+			sp.EndValue += float64(value)
+		}
+		file.Profiles = append(file.Profiles, sp)
+	}
+	// Default to the sample type pprof itself would show first.
+	if def := p.DefaultSampleType; def != "" {
+		for i, st := range p.SampleType {
+			if st.Type == def {
+				file.ActiveProfileIndex = i
+			}
+		}
+	}
+	if file.Shared.Frames == nil {
+		file.Shared.Frames = []speedscopeFrame{}
+	}
+
+	return json.NewEncoder(w).Encode(file)
+}
+
+// speedscopeUnit maps a pprof unit to the closest speedscope unit.
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	}
+	return "none"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+const testSpeedscopeEvented = `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "main", "file": "main.c", "line": 3}, {"name": "work"}, {"name": "io"}]},
+  "profiles": [{
+    "type": "evented", "name": "thread 1", "unit": "milliseconds", "startValue": 0, "endValue": 10,
+    "events": [
+      {"type": "O", "frame": 0, "at": 0},
+      {"type": "O", "frame": 1, "at": 1},
+      {"type": "O", "frame": 2, "at": 4},
+      {"type": "C", "frame": 2, "at": 6},
+      {"type": "C", "frame": 1, "at": 9},
+      {"type": "C", "frame": 0, "at": 10}
+    ]
+  }]
+}`
+
+func TestParseSpeedscopeEvented(t *testing.T) {
+	data, err := ParsePprofFile(strings.NewReader(testSpeedscopeEvented), nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	view := data.Views[0]
+	if view.Unit != "nanoseconds" || view.TotalValue != 10e6 {
+		t.Errorf("view %s total = %d, want 10ms in nanoseconds", view.Name, view.TotalValue)
+	}
+	work, io := findNode(view, "work"), findNode(view, "io")
+	if work.FlatValue != 6e6 || work.CumValue != 8e6 {
+		t.Errorf("work flat/cum = %d/%d, want 6ms/8ms", work.FlatValue, work.CumValue)
+	}
+	if work.Out[io] != 2e6 {
+		t.Errorf("work -> io = %d, want 2ms", work.Out[io])
+	}
+	if main := findNode(view, "main"); main.FileName != "main.c" {
+		t.Errorf("main file = %q, want main.c", main.FileName)
+	}
+}
+
+func TestSpeedscopeRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSpeedscope(&buf, newTestProfile(t), "test"); err != nil {
+		t.Fatalf("WriteSpeedscope: %v", err)
+	}
+	if got := detectFormat(buf.Bytes()); got != formatSpeedscope {
+		t.Fatalf("exported file detected as %s", got)
+	}
+	data, err := ParsePprofFile(&buf, nil)
+	if err != nil {
+		t.Fatalf("re-importing: %v", err)
+	}
+	want := parseTestProfile(t, newTestProfile(t)).Views[0]
+	got := data.Views[0]
+	for _, name := range []string{"main.main", "main.work", "main.helper"} {
+		w, g := findNode(want, name), findNode(got, name)
+		if g == nil || g.FlatValue != w.FlatValue || g.CumValue != w.CumValue {
+			t.Errorf("%s = %+v after round trip, want flat %d cum %d", name, g, w.FlatValue, w.CumValue)
+		}
+	}
+}
+
+func TestSpeedscopeRoundTripKeepsSampleTypes(t *testing.T) {
+	// A heap profile has two sample types per unit.
+	p := newTestProfile(t)
+	p.SampleType = []*profile.ValueType{
+		{Type: "alloc_objects", Unit: "count"},
+		{Type: "alloc_space", Unit: "bytes"},
+		{Type: "inuse_objects", Unit: "count"},
+		{Type: "inuse_space", Unit: "bytes"},
+	}
+	for i, s := range p.Sample {
+		n := int64(i + 1)
+		s.Value = []int64{10 * n, 1000 * n, n, 100 * n}
+	}
+	var buf bytes.Buffer
+	if err := WriteSpeedscope(&buf, p, "heap"); err != nil {
+		t.Fatalf("WriteSpeedscope: %v", err)
+	}
+	data, err := ParsePprofFile(&buf, nil)
+	if err != nil {
+		t.Fatalf("re-importing: %v", err)
+	}
+
+	want := parseTestProfile(t, p)
+	if len(data.Views) != len(want.Views) {
+		t.Fatalf("got %d views, want %d", len(data.Views), len(want.Views))
+	}
+	for i, w := range want.Views {
+		g := data.Views[i]
+		if g.Name != w.Name || g.TotalValue != w.TotalValue {
+			t.Errorf("view %d = %s with total %d, want %s with total %d", i, g.Name, g.TotalValue, w.Name, w.TotalValue)
+		}
+	}
+
+	// Files of other tools keep one sample type per unit, whatever their profiles are called.
+	buf.Reset()
+	if err := WriteSpeedscope(&buf, p, "heap"); err != nil {
+		t.Fatal(err)
+	}
+	other := strings.Replace(buf.String(), `"exporter":"pproftui"`, `"exporter":"other"`, 1)
+	data, err = ParsePprofFile(strings.NewReader(other), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Views) != 2 {
+		t.Errorf("file of another exporter gave %d views, want samples and space", len(data.Views))
+	}
+}
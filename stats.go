@@ -0,0 +1,262 @@
+// stats.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/google/pprof/profile"
+)
+
+// significanceLevel is the p-value below which a change is considered real
+// rather than run-to-run noise.
+const significanceLevel = 0.05
+
+// DiffStats summarizes how a function's value changed across repeated runs,
+// similar to what benchstat reports for benchmarks.
+type DiffStats struct {
+	BeforeRuns, AfterRuns int
+	MeanDelta             float64 // Mean of the after runs minus the mean of the before runs.
+	CILow, CIHigh         float64 // 95% confidence interval of MeanDelta.
+	PValue                float64 // Two-sided p-value of Welch's t-test.
+}
+
+// Significant reports whether the change is outside run-to-run noise.
+func (s *DiffStats) Significant() bool {
+	return s.PValue < significanceLevel
+}
+
+// DiffPprofSets diffs two sets of repeated profiles, such as several baseline
+// and candidate benchmark runs. Views show the difference between the mean
+// profiles of each set, and every function gets a significance test of its
+// per-run values.
+func DiffPprofSets(beforeReaders, afterReaders []io.Reader, symbolizer *Symbolizer) (*ProfileData, error) {
+	beforeRuns, beforeMean, err := parseProfileSet(beforeReaders, symbolizer)
+	if err != nil {
+		return nil, fmt.Errorf("could not load 'before' profiles: %w", err)
+	}
+	afterRuns, afterMean, err := parseProfileSet(afterReaders, symbolizer)
+	if err != nil {
+		return nil, fmt.Errorf("could not load 'after' profiles: %w", err)
+	}
+	data, err := diffProfileData(beforeMean, afterMean)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, view := range data.Views {
+		beforeIndex := indexRuns(beforeRuns, view.SampleType)
+		afterIndex := indexRuns(afterRuns, view.SampleType)
+		for _, node := range view.Nodes {
+			sig := funcSignature(node)
+			beforeFlat, beforeCum := runValues(beforeIndex, sig)
+			afterFlat, afterCum := runValues(afterIndex, sig)
+			node.FlatStats = welchTTest(beforeFlat, afterFlat)
+			node.CumStats = welchTTest(beforeCum, afterCum)
+		}
+	}
+	return data, nil
+}
+
+// parseProfileSet parses every run of a set and builds the mean profile of the set.
+func parseProfileSet(readers []io.Reader, symbolizer *Symbolizer) ([]*ProfileData, *ProfileData, error) {
+	if len(readers) == 0 {
+		return nil, nil, fmt.Errorf("no profiles given")
+	}
+	runs := make([]*ProfileData, 0, len(readers))
+	profiles := make([]*profile.Profile, 0, len(readers))
+	for i, reader := range readers {
+		run, err := ParsePprofFile(reader, symbolizer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("profile %d: %w", i+1, err)
+		}
+		runs = append(runs, run)
+		profiles = append(profiles, run.RawPprof)
+	}
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not merge profiles: %w", err)
+	}
+	merged.Scale(1 / float64(len(profiles)))
+	merged.DurationNanos /= int64(len(profiles))
+
+	mean, err := NewProfileData(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	mean.MergedCount = len(profiles)
+	return runs, mean, nil
+}
+
+// indexRuns maps every function signature to its flat and cumulative value in
+// each run, for the view of the given sample type.
+func indexRuns(runs []*ProfileData, sampleType string) []map[string]LineCost {
+	index := make([]map[string]LineCost, len(runs))
+	for i, run := range runs {
+		index[i] = make(map[string]LineCost)
+		for _, view := range run.Views {
+			if view.SampleType != sampleType {
+				continue
+			}
+			for _, node := range view.Nodes {
+				sig := funcSignature(node)
+				cost := index[i][sig]
+				cost.Flat += node.FlatValue
+				cost.Cum += node.CumValue
+				index[i][sig] = cost
+			}
+			break
+		}
+	}
+	return index
+}
+
+// runValues returns the flat and cumulative value of a function in every run.
+// Runs where the function does not appear count as zero.
+func runValues(index []map[string]LineCost, sig string) (flat, cum []float64) {
+	flat = make([]float64, len(index))
+	cum = make([]float64, len(index))
+	for i, costs := range index {
+		flat[i] = float64(costs[sig].Flat)
+		cum[i] = float64(costs[sig].Cum)
+	}
+	return flat, cum
+}
+
+// welchTTest compares the means of two samples without assuming equal
+// variances. It returns nil when either side has fewer than two runs.
+func welchTTest(before, after []float64) *DiffStats {
+	n1, n2 := float64(len(before)), float64(len(after))
+	if n1 < 2 || n2 < 2 {
+		return nil
+	}
+	m1, v1 := meanVariance(before)
+	m2, v2 := meanVariance(after)
+	stats := &DiffStats{
+		BeforeRuns: len(before),
+		AfterRuns:  len(after),
+		MeanDelta:  m2 - m1,
+	}
+
+	se2 := v1/n1 + v2/n2
+	if se2 == 0 {
+		// Every run measured exactly the same value: any difference is real.
+		stats.CILow, stats.CIHigh = stats.MeanDelta, stats.MeanDelta
+		stats.PValue = 1
+		if stats.MeanDelta != 0 {
+			stats.PValue = 0
+		}
+		return stats
+	}
+
+	// Welch–Satterthwaite degrees of freedom.
+	df := se2 * se2 / ((v1/n1)*(v1/n1)/(n1-1) + (v2/n2)*(v2/n2)/(n2-1))
+	se := math.Sqrt(se2)
+	stats.PValue = studentTwoSidedP(stats.MeanDelta/se, df)
+	half := studentQuantile(1-significanceLevel/2, df) * se
+	stats.CILow, stats.CIHigh = stats.MeanDelta-half, stats.MeanDelta+half
+	return stats
+}
+
+// meanVariance returns the mean and the unbiased sample variance.
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+	return mean, variance
+}
+
+// studentTwoSidedP returns P(|T| >= |t|) for Student's t-distribution with df degrees of freedom.
+func studentTwoSidedP(t, df float64) float64 {
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// studentQuantile returns the value t such that P(T <= t) = p, for p > 0.5.
+func studentQuantile(p, df float64) float64 {
+	target := 2 * (1 - p) // Two-sided tail probability.
+	lo, hi := 0.0, 1e3
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if studentTwoSidedP(mid, df) > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta computes the regularized incomplete beta function I_x(a, b)
+// using its continued fraction expansion.
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on one side of the mean.
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete
+// beta function with the modified Lentz method.
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= maxIterations; m++ {
+		// Even step.
+		num := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step.
+		num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
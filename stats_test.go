@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+func TestStudentDistribution(t *testing.T) {
+	if got := studentTwoSidedP(2, 10); math.Abs(got-0.07339) > 1e-4 {
+		t.Errorf("studentTwoSidedP(2, 10) = %.5f, want 0.07339", got)
+	}
+	if got := studentTwoSidedP(0, 5); math.Abs(got-1) > 1e-9 {
+		t.Errorf("studentTwoSidedP(0, 5) = %.5f, want 1", got)
+	}
+	if got := studentQuantile(0.975, 10); math.Abs(got-2.2281) > 1e-3 {
+		t.Errorf("studentQuantile(0.975, 10) = %.4f, want 2.2281", got)
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	noisy := welchTTest([]float64{10, 12, 9, 11, 13}, []float64{11, 10, 12, 13, 9})
+	if noisy == nil || noisy.Significant() {
+		t.Errorf("overlapping runs should not be significant, got %+v", noisy)
+	}
+	real := welchTTest([]float64{10, 11, 9, 10, 10}, []float64{20, 21, 19, 20, 22})
+	if real == nil || !real.Significant() {
+		t.Fatalf("clearly separated runs should be significant, got %+v", real)
+	}
+	if real.CILow > real.MeanDelta || real.CIHigh < real.MeanDelta || real.CILow <= 0 {
+		t.Errorf("confidence interval [%v, %v] should contain %v and exclude 0", real.CILow, real.CIHigh, real.MeanDelta)
+	}
+	if welchTTest([]float64{1}, []float64{2, 3}) != nil {
+		t.Error("a single run cannot be tested")
+	}
+}
+
+func TestDiffPprofSets(t *testing.T) {
+	var before, after []io.Reader
+	for i, jitter := range []int64{-1, 0, 1} {
+		b := newTestProfile(t)
+		b.Sample[0].Value[0] += jitter // main.helper's cost varies a little between runs
+		before = append(before, writeTestProfile(t, b))
+
+		a := newTestProfile(t)
+		a.Sample[0].Value[0] += 20 + int64(i) // and grows well beyond that variation
+		after = append(after, writeTestProfile(t, a))
+	}
+
+	data, err := DiffPprofSets(before, after, nil)
+	if err != nil {
+		t.Fatalf("DiffPprofSets: %v", err)
+	}
+	view := data.Views[0]
+	helper := findNode(view, "main.helper")
+	if helper.CumDelta != 21 {
+		t.Errorf("main.helper mean delta = %d, want 21", helper.CumDelta)
+	}
+	if helper.CumStats == nil || !helper.CumStats.Significant() {
+		t.Errorf("main.helper change should be significant, got %+v", helper.CumStats)
+	}
+	if main := findNode(view, "main.main"); main.FlatStats == nil || main.FlatStats.Significant() {
+		t.Errorf("main.main has no flat cost in any run, got %+v", main.FlatStats)
+	}
+}
@@ -11,7 +11,10 @@ type Styles struct {
 	Header lipgloss.Style
 	DiffPositive,
 	DiffNegative,
-	ProjectCode lipgloss.Style
+	ProjectCode,
+	StdlibCode,
+	RuntimeCode,
+	ThirdPartyCode lipgloss.Style
 }
 
 func defaultStyles() Styles {
@@ -34,5 +37,21 @@ func defaultStyles() Styles {
 	s.DiffNegative = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))  // Red
 
 	s.ProjectCode = lipgloss.NewStyle().Foreground(lipgloss.Color("86")) // A nice cyan/light blue
+	s.StdlibCode = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	s.RuntimeCode = lipgloss.NewStyle().Foreground(lipgloss.Color("244")) // Dimmed, it is rarely the culprit
+	s.ThirdPartyCode = lipgloss.NewStyle().Foreground(lipgloss.Color("179"))
 	return s
 }
+
+// classStyle returns the style function names of a code class are shown in.
+func (s *Styles) classStyle(c CodeClass) lipgloss.Style {
+	switch c {
+	case classProject:
+		return s.ProjectCode
+	case classStdlib:
+		return s.StdlibCode
+	case classRuntime:
+		return s.RuntimeCode
+	}
+	return s.ThirdPartyCode
+}
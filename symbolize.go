@@ -0,0 +1,330 @@
+// symbolize.go
+package main
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/pprof/profile"
+)
+
+// Symbolizer resolves the addresses of unsymbolized locations, as recorded by
+// stripped binaries and non-Go runtimes, using local ELF files. It is safe
+// for concurrent use, as live mode may parse several profiles at once.
+type Symbolizer struct {
+	binaries []*Binary
+	byID     map[string]*Binary
+	opened   []*Binary // The binaries NewSymbolizer opened itself, closed by Close.
+
+	mu       sync.Mutex
+	resolved int // Locations symbolized so far.
+}
+
+// NewSymbolizer uses the given binaries, already opened by the caller, and
+// opens the ELF files at paths. Directories are searched recursively, and
+// files in them that are not ELF binaries are skipped.
+func NewSymbolizer(binaries []*Binary, paths []string) (*Symbolizer, error) {
+	s := &Symbolizer{byID: make(map[string]*Binary)}
+	for _, b := range binaries {
+		s.add(b)
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		if !info.IsDir() {
+			b, err := OpenBinary(path)
+			if err != nil {
+				s.Close()
+				return nil, err
+			}
+			s.opened = append(s.opened, b)
+			s.add(b)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if b, err := OpenBinary(file); err == nil {
+				s.opened = append(s.opened, b)
+				s.add(b)
+			}
+			return nil
+		})
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	if len(s.binaries) == 0 {
+		return nil, fmt.Errorf("no ELF binaries found in %s", strings.Join(paths, ", "))
+	}
+	return s, nil
+}
+
+func (s *Symbolizer) add(b *Binary) {
+	s.binaries = append(s.binaries, b)
+	if b.BuildID != "" {
+		s.byID[b.BuildID] = b
+	}
+}
+
+// Close releases the binaries the symbolizer opened. Those passed to
+// NewSymbolizer stay open.
+func (s *Symbolizer) Close() {
+	for _, b := range s.opened {
+		b.Close()
+	}
+}
+
+// Resolved returns the number of locations symbolized so far.
+func (s *Symbolizer) Resolved() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resolved
+}
+
+// binaryFor finds the binary a mapping was loaded from: the one with the same
+// build ID, or else one with the same file name whose build ID does not
+// contradict the mapping's. A single binary is assumed to be the main
+// executable of profiles that record no build IDs or file names at all.
+func (s *Symbolizer) binaryFor(m *profile.Mapping, isMain bool) *Binary {
+	id := strings.ToLower(m.BuildID)
+	if b, ok := s.byID[id]; ok && id != "" {
+		return b
+	}
+	for _, b := range s.binaries {
+		if m.File != "" && filepath.Base(m.File) == filepath.Base(b.Path) && (id == "" || b.BuildID == "") {
+			return b
+		}
+	}
+	if isMain && len(s.binaries) == 1 && id == "" && m.File == "" {
+		return s.binaries[0]
+	}
+	return nil
+}
+
+// Symbolize gives every unsymbolized location of p whose mapping matches one
+// of the binaries a function, file and line. It returns the number of
+// locations it resolved.
+func (s *Symbolizer) Symbolize(p *profile.Profile) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	functions := make(map[[2]string]*profile.Function)
+	var nextID uint64
+	for _, fn := range p.Function {
+		functions[[2]string{fn.Name, fn.Filename}] = fn
+		nextID = max(nextID, fn.ID)
+	}
+
+	resolved := 0
+	for _, loc := range p.Location {
+		if loc.Mapping == nil || !isUnsymbolized(loc) {
+			continue
+		}
+		b := s.binaryFor(loc.Mapping, len(p.Mapping) > 0 && loc.Mapping == p.Mapping[0])
+		if b == nil {
+			continue
+		}
+		name, file, line, start, ok := b.sourceLine(b.objAddr(loc))
+		if !ok {
+			continue
+		}
+		key := [2]string{name, file}
+		fn, ok := functions[key]
+		if !ok {
+			nextID++
+			fn = &profile.Function{
+				ID:         nextID,
+				Name:       name,
+				SystemName: name,
+				Filename:   file,
+				StartLine:  int64(start),
+			}
+			functions[key] = fn
+			p.Function = append(p.Function, fn)
+		}
+		loc.Line = []profile.Line{{Function: fn, Line: int64(line)}}
+		loc.Mapping.HasFunctions = true
+		if file != "" {
+			loc.Mapping.HasFilenames = true
+			loc.Mapping.HasLineNumbers = true
+		}
+		resolved++
+	}
+	s.resolved += resolved
+	return resolved
+}
+
+// isUnsymbolized reports whether a location has no function, or only the
+// placeholder labelUnsymbolized gave it.
+func isUnsymbolized(loc *profile.Location) bool {
+	if len(loc.Line) == 0 {
+		return true
+	}
+	fn := loc.Line[0].Function
+	return len(loc.Line) == 1 && fn != nil && strings.HasPrefix(fn.Name, "[unknown]")
+}
+
+// labelUnsymbolized gives the locations still without a function a placeholder
+// named after their binary, "[unknown] (libfoo.so)", like perf does. Without
+// it their cost would silently disappear from every view.
+func labelUnsymbolized(p *profile.Profile) {
+	functions := make(map[string]*profile.Function)
+	var nextID uint64
+	for _, fn := range p.Function {
+		nextID = max(nextID, fn.ID)
+	}
+	for _, loc := range p.Location {
+		if len(loc.Line) > 0 {
+			continue
+		}
+		binary := "unmapped"
+		if loc.Mapping != nil && loc.Mapping.File != "" {
+			binary = filepath.Base(loc.Mapping.File)
+		}
+		fn, ok := functions[binary]
+		if !ok {
+			nextID++
+			name := fmt.Sprintf("[unknown] (%s)", binary)
+			fn = &profile.Function{ID: nextID, Name: name, SystemName: name}
+			functions[binary] = fn
+			p.Function = append(p.Function, fn)
+		}
+		loc.Line = []profile.Line{{Function: fn}}
+	}
+}
+
+// stripUnsymbolized returns a copy of p without the placeholders of
+// labelUnsymbolized, so that a saved profile can still be symbolized by pprof.
+// Only locations that keep a mapping and an address to resolve are stripped.
+func stripUnsymbolized(p *profile.Profile) *profile.Profile {
+	isPlaceholder := func(loc *profile.Location) bool {
+		return loc.Mapping != nil && loc.Address != 0 && len(loc.Line) == 1 && isUnsymbolized(loc)
+	}
+	if !slices.ContainsFunc(p.Location, isPlaceholder) {
+		return p
+	}
+	out := p.Copy()
+	placeholders := make(map[*profile.Function]bool)
+	for _, loc := range out.Location {
+		if isPlaceholder(loc) {
+			placeholders[loc.Line[0].Function] = true
+			loc.Line = nil
+		}
+	}
+	// Drop the placeholder functions no location refers to anymore.
+	for _, loc := range out.Location {
+		for _, line := range loc.Line {
+			delete(placeholders, line.Function)
+		}
+	}
+	out.Function = slices.DeleteFunc(out.Function, func(fn *profile.Function) bool { return placeholders[fn] })
+	return out
+}
+
+// readBuildID returns the GNU build ID of an ELF file in hex, the form pprof
+// records in Mapping.BuildID, or "" if the file has none.
+func readBuildID(f *elf.File) string {
+	section := f.Section(".note.gnu.build-id")
+	if section == nil {
+		return ""
+	}
+	data, err := section.Data()
+	if err != nil || len(data) < 12 {
+		return ""
+	}
+	// An ELF note is a name size, a descriptor size and a type, followed by
+	// the name and the descriptor, each padded to four bytes.
+	nameSize := uint64(f.ByteOrder.Uint32(data[0:]))
+	descSize := uint64(f.ByteOrder.Uint32(data[4:]))
+	descStart := 12 + (nameSize+3)&^3
+	if descStart+descSize > uint64(len(data)) {
+		return ""
+	}
+	return hex.EncodeToString(data[descStart : descStart+descSize])
+}
+
+// sourceLine resolves an address to the function containing it, its source
+// file and line, and the line the function starts at. It prefers the Go
+// pclntab, then DWARF, then the bare symbol table.
+func (b *Binary) sourceLine(addr uint64) (name, file string, line, start int, ok bool) {
+	if b.lines != nil {
+		if pcFile, pcLine, fn := b.lines.PCToLine(addr); fn != nil {
+			_, entryLine, _ := b.lines.PCToLine(fn.Entry)
+			return fn.Name, pcFile, pcLine, entryLine, true
+		}
+	}
+	name, entry := b.symbolAt(addr)
+	if name == "" {
+		return "", "", 0, 0, false
+	}
+	file, line = b.dwarfLine(addr)
+	_, start = b.dwarfLine(entry)
+	return name, file, line, start, true
+}
+
+// dwarfUnit is a compilation unit of a binary's DWARF data and the address
+// ranges its code covers.
+type dwarfUnit struct {
+	entry  *dwarf.Entry
+	ranges [][2]uint64
+}
+
+// dwarfLine looks an address up in the DWARF line tables, which are loaded on
+// first use. It returns an empty file name when the binary has no DWARF.
+func (b *Binary) dwarfLine(addr uint64) (string, int) {
+	b.dwarfOnce.Do(func() {
+		b.dwarf, b.dwarfUnits = loadDWARFUnits(b.file)
+	})
+	for _, unit := range b.dwarfUnits {
+		for _, r := range unit.ranges {
+			if addr < r[0] || addr >= r[1] {
+				continue
+			}
+			lr, err := b.dwarf.LineReader(unit.entry)
+			if err != nil || lr == nil {
+				return "", 0
+			}
+			var entry dwarf.LineEntry
+			if err := lr.SeekPC(addr, &entry); err != nil {
+				return "", 0
+			}
+			return entry.File.Name, entry.Line
+		}
+	}
+	return "", 0
+}
+
+func loadDWARFUnits(f *elf.File) (*dwarf.Data, []dwarfUnit) {
+	d, err := f.DWARF()
+	if err != nil {
+		return nil, nil
+	}
+	var units []dwarfUnit
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil || entry == nil {
+			break
+		}
+		if entry.Tag == dwarf.TagCompileUnit {
+			if ranges, err := d.Ranges(entry); err == nil && len(ranges) > 0 {
+				units = append(units, dwarfUnit{entry: entry, ranges: ranges})
+			}
+		}
+		r.SkipChildren()
+	}
+	return d, units
+}
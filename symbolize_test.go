@@ -0,0 +1,179 @@
+package main
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+//go:noinline
+func symbolizeTestTarget() int { return 42 }
+
+// strippedTestProfile returns a profile with one sample in symbolizeTestTarget
+// that only records its address in the test binary, like profiles of stripped
+// binaries do.
+func strippedTestProfile(t *testing.T, exe string, buildID string) *profile.Profile {
+	t.Helper()
+	mapping := &profile.Mapping{ID: 1, File: exe, BuildID: buildID, Limit: ^uint64(0)}
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		Mapping:    []*profile.Mapping{mapping},
+		Location: []*profile.Location{
+			{ID: 1, Mapping: mapping, Address: uint64(reflect.ValueOf(symbolizeTestTarget).Pointer())},
+			{ID: 2, Mapping: mapping, Address: 0x10},
+		},
+	}
+}
+
+func TestSymbolize(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	s, err := NewSymbolizer(nil, []string{exe})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer s.Close()
+	if s.binaries[0].file.Type == elf.ET_DYN {
+		t.Skip("test binary is position independent")
+	}
+
+	p := strippedTestProfile(t, "/elsewhere/"+strings.TrimPrefix(exe, "/"), s.binaries[0].BuildID)
+	p.Sample = []*profile.Sample{
+		{Location: []*profile.Location{p.Location[0]}, Value: []int64{3}},
+		{Location: []*profile.Location{p.Location[1]}, Value: []int64{1}},
+	}
+	if n := s.Symbolize(p); n != 1 {
+		t.Fatalf("symbolized %d locations, want 1", n)
+	}
+	labelUnsymbolized(p)
+
+	data, err := NewProfileData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test binaries name package main after its import path.
+	target := findNode(data.Views[0], "github.com/Oloruntobi1/pproftui.symbolizeTestTarget")
+	if target == nil || target.FlatValue != 3 {
+		t.Fatalf("symbolizeTestTarget = %+v, want flat 3", target)
+	}
+	if !strings.HasSuffix(target.FileName, "symbolize_test.go") || target.StartLine == 0 {
+		t.Errorf("file:line = %s:%d, want symbolize_test.go", target.FileName, target.StartLine)
+	}
+	if unknown := findNode(data.Views[0], "[unknown] ("+filepath.Base(exe)+")"); unknown == nil || unknown.FlatValue != 1 {
+		t.Errorf("unresolved address should be kept as an [unknown] frame, got %+v", unknown)
+	}
+	if !p.Mapping[0].HasFunctions {
+		t.Error("mapping should be marked as having functions")
+	}
+}
+
+func TestSymbolizeSkipsMismatchedBuildID(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	s, err := NewSymbolizer(nil, []string{exe})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer s.Close()
+	if s.binaries[0].BuildID == "" {
+		t.Skip("test binary has no GNU build ID")
+	}
+
+	p := strippedTestProfile(t, exe, "00112233")
+	if n := s.Symbolize(p); n != 0 {
+		t.Errorf("symbolized %d locations from a binary with another build ID", n)
+	}
+}
+
+func TestSymbolizerSharesOpenedBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	b, err := OpenBinary(exe)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer b.Close()
+	if b.file.Type == elf.ET_DYN {
+		t.Skip("test binary is position independent")
+	}
+	s, err := NewSymbolizer([]*Binary{b}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Live mode parses profiles in overlapping commands.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Symbolize(strippedTestProfile(t, exe, b.BuildID))
+		}()
+	}
+	wg.Wait()
+	if got := s.Resolved(); got != 4 {
+		t.Errorf("resolved %d locations, want 4", got)
+	}
+
+	s.Close()
+	if _, err := b.file.Section(".text").Data(); err != nil {
+		t.Errorf("closing the symbolizer closed the caller's binary: %v", err)
+	}
+}
+
+func TestExportKeepsUnsymbolizedAddresses(t *testing.T) {
+	p := strippedTestProfile(t, "/usr/bin/svc", "")
+	p.Sample = []*profile.Sample{{Location: p.Location, Value: []int64{1}}}
+	labelUnsymbolized(p)
+
+	path := filepath.Join(t.TempDir(), "out.pb.gz")
+	if err := exportProfile(path, p); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := profile.Parse(mustOpen(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loc := range saved.Location {
+		if len(loc.Line) != 0 || loc.Address == 0 {
+			t.Errorf("location %d = %+v, want a bare address", loc.ID, loc)
+		}
+	}
+	if len(saved.Function) != 0 {
+		t.Errorf("placeholder functions were saved: %v", saved.Function)
+	}
+	if len(p.Location[0].Line) != 1 {
+		t.Error("the loaded profile should keep its placeholders")
+	}
+
+	// Loading the saved profile labels the addresses again.
+	data, err := ParsePprofFile(mustOpen(t, path), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := findNode(data.Views[0], "[unknown] (svc)"); node == nil || node.FlatValue != 1 {
+		t.Errorf("[unknown] (svc) = %+v, want flat 1", node)
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,116 @@
+// trace.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// traceProfiles are the profiles `go tool trace` can derive from an execution
+// trace, each becoming one sample type of the combined profile.
+var traceProfiles = []struct {
+	mode       string // Value of go tool trace's -pprof flag.
+	sampleType string
+}{
+	{"net", "net_blocking"},
+	{"sync", "sync_blocking"},
+	{"syscall", "syscall_blocking"},
+	{"sched", "sched_latency"},
+}
+
+// isGoTrace reports whether data is a runtime/trace file, which starts with a
+// header like "go 1.22 trace\x00\x00\x00".
+func isGoTrace(data []byte) bool {
+	head := data
+	if len(head) > 16 {
+		head = head[:16]
+	}
+	return bytes.HasPrefix(head, []byte("go 1.")) && bytes.Contains(head, []byte(" trace\x00"))
+}
+
+// ParseGoTrace builds the off-CPU profiles of an execution trace: time blocked
+// on the network, on synchronization and in syscalls, and time spent runnable
+// before being scheduled. It uses `go tool trace` from the installed Go
+// toolchain, which understands every trace format version.
+func ParseGoTrace(data []byte) (*profile.Profile, error) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		return nil, fmt.Errorf("reading execution traces needs the go command in PATH: %w", err)
+	}
+	tmp, err := os.CreateTemp("", "pproftui-*.trace")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sampleTypes := make([]*profile.ValueType, len(traceProfiles))
+	for i, tp := range traceProfiles {
+		sampleTypes[i] = &profile.ValueType{Type: tp.sampleType, Unit: "nanoseconds"}
+	}
+
+	profiles := make([]*profile.Profile, 0, len(traceProfiles))
+	for i, tp := range traceProfiles {
+		cmd := exec.Command(goTool, "tool", "trace", "-pprof="+tp.mode, tmp.Name())
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go tool trace -pprof=%s: %w: %s", tp.mode, err, strings.TrimSpace(stderr.String()))
+		}
+		p, err := profile.ParseData(out)
+		if err != nil {
+			return nil, fmt.Errorf("go tool trace -pprof=%s: %w", tp.mode, err)
+		}
+		if err := spreadTraceProfile(p, i, sampleTypes); err != nil {
+			return nil, fmt.Errorf("go tool trace -pprof=%s: %w", tp.mode, err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not combine trace profiles: %w", err)
+	}
+	if len(merged.Sample) == 0 {
+		return nil, errors.New("the trace has no blocking or scheduling events")
+	}
+	return merged, nil
+}
+
+// spreadTraceProfile rewrites one of go tool trace's profiles so that its delay
+// lands in the sample type at index and every other sample type is zero. The
+// profiles can then be merged into one with a view per kind of waiting.
+func spreadTraceProfile(p *profile.Profile, index int, sampleTypes []*profile.ValueType) error {
+	delay := -1
+	for i, st := range p.SampleType {
+		if st.Unit == "nanoseconds" {
+			delay = i
+		}
+	}
+	if delay < 0 {
+		return errors.New("profile has no delay sample type")
+	}
+	for _, s := range p.Sample {
+		values := make([]int64, len(sampleTypes))
+		values[index] = s.Value[delay]
+		s.Value = values
+	}
+	p.SampleType = sampleTypes
+	p.DefaultSampleType = ""
+	p.PeriodType = &profile.ValueType{Type: "trace", Unit: "nanoseconds"}
+	p.Period = 1
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseGoTrace(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatalf("trace.Start: %v", err)
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			time.Sleep(2 * time.Millisecond)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	trace.Stop()
+
+	if got := detectFormat(buf.Bytes()); got != formatGoTrace {
+		t.Fatalf("trace detected as %s", got)
+	}
+	data, err := ParsePprofFile(&buf, nil)
+	if err != nil {
+		t.Fatalf("ParsePprofFile: %v", err)
+	}
+	if len(data.Views) != len(traceProfiles) {
+		t.Fatalf("got %d views, want one per trace profile", len(data.Views))
+	}
+	for i, tp := range traceProfiles {
+		if data.Views[i].SampleType != tp.sampleType {
+			t.Errorf("view %d is %s, want %s", i, data.Views[i].SampleType, tp.sampleType)
+		}
+	}
+	if sync := data.Views[1]; sync.TotalValue < int64(2*time.Millisecond) {
+		t.Errorf("sync blocking = %v, want at least the time spent waiting for the mutex", time.Duration(sync.TotalValue))
+	}
+}